@@ -3,7 +3,10 @@ module github.com/chrispypip/spider
 go 1.22.12
 
 require (
-	github.com/godbus/dbus/v5 v5.1.0 // indirect
-	github.com/sirupsen/logrus v1.9.3 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	github.com/godbus/dbus/v5 v5.1.0
+	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/crypto v0.31.0
+	golang.org/x/term v0.27.0
 )
+
+require golang.org/x/sys v0.28.0 // indirect
@@ -226,7 +226,7 @@ func (ap *AccessPoint) Stop() error {
 }
 
 func (ap *AccessPoint) StartProfile(ssid string) error {
-	if err := ap.obj.Call(accessPointMethodStartProfile, 0).Err; err != nil {
+	if err := ap.obj.Call(accessPointMethodStartProfile, 0, ssid).Err; err != nil {
 		apLogger.WithFields(log.Fields{
 			"err": err,
 		}).Errorf("Failed to start Access Point profile: SSID %s", ssid)
@@ -0,0 +1,162 @@
+package spider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultAccessPointClientPollInterval = 5 * time.Second
+	accessPointClientEventCapacity       = 16
+)
+
+type AccessPointClientEventType string
+
+const (
+	AccessPointClientJoined AccessPointClientEventType = "ClientJoined"
+	AccessPointClientLeft   AccessPointClientEventType = "ClientLeft"
+)
+
+type AccessPointClientEvent struct {
+	Type     AccessPointClientEventType
+	Client   *AccessPointClient
+	Time     time.Time
+	Duration time.Duration
+}
+
+type TrackedAccessPointClient struct {
+	Client   *AccessPointClient
+	JoinedAt time.Time
+}
+
+type AccessPointClientTracker struct {
+	diagnostic AccessPointDiagnosticer
+	mu         sync.Mutex
+	clients    map[string]*TrackedAccessPointClient
+	logger     *log.Entry
+}
+
+func NewAccessPointClientTracker(diagnostic AccessPointDiagnosticer) *AccessPointClientTracker {
+	log.SetReportCaller(true)
+	return &AccessPointClientTracker{
+		diagnostic: diagnostic,
+		clients:    make(map[string]*TrackedAccessPointClient),
+		logger: log.WithFields(log.Fields{
+			"type": "AccessPointClientTracker",
+			"path": diagnostic.GetPath(),
+		}),
+	}
+}
+
+func (t *AccessPointClientTracker) GetPath() dbus.ObjectPath {
+	return t.diagnostic.GetPath()
+}
+
+func (t *AccessPointClientTracker) GetClients() []*TrackedAccessPointClient {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clients := make([]*TrackedAccessPointClient, 0, len(t.clients))
+	for _, c := range t.clients {
+		tracked := *c
+		clients = append(clients, &tracked)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].JoinedAt.Before(clients[j].JoinedAt)
+	})
+	return clients
+}
+
+func (t *AccessPointClientTracker) Poll() ([]*AccessPointClientEvent, error) {
+	clients, err := t.diagnostic.GetDiagnostics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access point diagnostics: %s", err)
+	}
+	return t.update(clients, time.Now()), nil
+}
+
+func (t *AccessPointClientTracker) Watch(ctx context.Context, interval time.Duration) <-chan *AccessPointClientEvent {
+	if interval <= 0 {
+		interval = DefaultAccessPointClientPollInterval
+	}
+	events := make(chan *AccessPointClientEvent, accessPointClientEventCapacity)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			polled, err := t.Poll()
+			if err != nil {
+				t.logger.WithFields(log.Fields{
+					"err": err,
+				}).Warn("failed to poll access point clients")
+			}
+			for _, event := range polled {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+func (t *AccessPointClientTracker) update(clients []*AccessPointClient, now time.Time) []*AccessPointClientEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	events := make([]*AccessPointClientEvent, 0)
+	seen := make(map[string]bool, len(clients))
+	for _, c := range clients {
+		seen[c.Address] = true
+		if tracked, ok := t.clients[c.Address]; ok {
+			tracked.Client = c
+			continue
+		}
+		joinedAt := now
+		if c.ConnectedTime != nil {
+			joinedAt = now.Add(-time.Duration(*c.ConnectedTime) * time.Second)
+		}
+		t.clients[c.Address] = &TrackedAccessPointClient{
+			Client:   c,
+			JoinedAt: joinedAt,
+		}
+		t.logger.Infof("Client %s joined", c.Address)
+		events = append(events, &AccessPointClientEvent{
+			Type:   AccessPointClientJoined,
+			Client: c,
+			Time:   joinedAt,
+		})
+	}
+	left := make([]string, 0)
+	for address := range t.clients {
+		if !seen[address] {
+			left = append(left, address)
+		}
+	}
+	sort.Strings(left)
+	for _, address := range left {
+		tracked := t.clients[address]
+		delete(t.clients, address)
+		duration := now.Sub(tracked.JoinedAt)
+		t.logger.Infof("Client %s left after %s", address, duration)
+		events = append(events, &AccessPointClientEvent{
+			Type:     AccessPointClientLeft,
+			Client:   tracked.Client,
+			Time:     now,
+			Duration: duration,
+		})
+	}
+	return events
+}
@@ -0,0 +1,161 @@
+package spider
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+type testAccessPointDiagnostic struct {
+	clients []*AccessPointClient
+}
+
+func (d *testAccessPointDiagnostic) GetPath() dbus.ObjectPath {
+	return testDevicePath
+}
+
+func (d *testAccessPointDiagnostic) GetInterface() string {
+	return "net.connman.iwd.AccessPointDiagnostic"
+}
+
+func (d *testAccessPointDiagnostic) GetDiagnostics() ([]*AccessPointClient, error) {
+	return d.clients, nil
+}
+
+func testAccessPointClient(address string, connectedTime *uint32) *AccessPointClient {
+	return &AccessPointClient{Address: address, ConnectedTime: connectedTime}
+}
+
+func TestAccessPointClientTrackerUpdate(t *testing.T) {
+	tracker := NewAccessPointClientTracker(&testAccessPointDiagnostic{})
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	connectedTime := uint32(90)
+
+	events := tracker.update([]*AccessPointClient{
+		testAccessPointClient("02:00:00:00:00:0c", nil),
+		testAccessPointClient("02:00:00:00:00:0a", &connectedTime),
+		testAccessPointClient("02:00:00:00:00:0b", nil),
+	}, start)
+	if len(events) != 3 {
+		t.Fatalf("first update produced %d events; want 3", len(events))
+	}
+	for _, event := range events {
+		if event.Type != AccessPointClientJoined {
+			t.Errorf("first update event = %+v; want %s", event, AccessPointClientJoined)
+		}
+	}
+	if joined := events[1]; joined.Client.Address != "02:00:00:00:00:0a" || !joined.Time.Equal(start.Add(-90*time.Second)) {
+		t.Errorf("client with ConnectedTime joined at %s; want %s", joined.Time, start.Add(-90*time.Second))
+	}
+	if joined := events[0]; !joined.Time.Equal(start) {
+		t.Errorf("client without ConnectedTime joined at %s; want %s", joined.Time, start)
+	}
+	clients := tracker.GetClients()
+	if len(clients) != 3 || clients[0].Client.Address != "02:00:00:00:00:0a" {
+		t.Errorf("GetClients = %v; want the backdated client first", clients)
+	}
+
+	later := start.Add(time.Minute)
+	updated := testAccessPointClient("02:00:00:00:00:0b", nil)
+	rssi := int16(-40)
+	updated.RSSI = &rssi
+	if events = tracker.update([]*AccessPointClient{
+		testAccessPointClient("02:00:00:00:00:0a", &connectedTime),
+		updated,
+		testAccessPointClient("02:00:00:00:00:0c", nil),
+	}, later); len(events) != 0 {
+		t.Errorf("unchanged clients produced events %v", events)
+	}
+	for _, c := range tracker.GetClients() {
+		if c.Client.Address == "02:00:00:00:00:0b" && (c.Client.RSSI == nil || !c.JoinedAt.Equal(start)) {
+			t.Errorf("tracked client = %+v; want refreshed diagnostics and the original join time", c)
+		}
+	}
+
+	end := start.Add(5 * time.Minute)
+	events = tracker.update([]*AccessPointClient{
+		testAccessPointClient("02:00:00:00:00:0d", nil),
+	}, end)
+	expected := []struct {
+		eventType AccessPointClientEventType
+		address   string
+		duration  time.Duration
+	}{
+		{eventType: AccessPointClientJoined, address: "02:00:00:00:00:0d"},
+		{eventType: AccessPointClientLeft, address: "02:00:00:00:00:0a", duration: 5*time.Minute + 90*time.Second},
+		{eventType: AccessPointClientLeft, address: "02:00:00:00:00:0b", duration: 5 * time.Minute},
+		{eventType: AccessPointClientLeft, address: "02:00:00:00:00:0c", duration: 5 * time.Minute},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("last update produced %d events; want %d", len(events), len(expected))
+	}
+	for i, e := range expected {
+		event := events[i]
+		if event.Type != e.eventType || event.Client.Address != e.address || event.Duration != e.duration {
+			t.Errorf("event %d = %s %s after %s; want %s %s after %s", i, event.Type, event.Client.Address, event.Duration, e.eventType, e.address, e.duration)
+		}
+		if e.eventType == AccessPointClientLeft && !event.Time.Equal(end) {
+			t.Errorf("event %d Time = %s; want %s", i, event.Time, end)
+		}
+	}
+	if clients = tracker.GetClients(); len(clients) != 1 || clients[0].Client.Address != "02:00:00:00:00:0d" {
+		t.Errorf("GetClients after leaving = %v", clients)
+	}
+}
+
+func TestDecodeAccessPointClient(t *testing.T) {
+	c, err := decodeAccessPointClient(map[string]dbus.Variant{
+		"Address":       dbus.MakeVariant("02:00:00:00:00:0a"),
+		"RSSI":          dbus.MakeVariant(int16(-45)),
+		"RxMode":        dbus.MakeVariant("HE"),
+		"RxMCS":         dbus.MakeVariant(uint8(9)),
+		"TxBitrate":     dbus.MakeVariant(uint32(1200000)),
+		"ConnectedTime": dbus.MakeVariant(uint32(42)),
+		"Unknown":       dbus.MakeVariant("ignored"),
+	})
+	if err != nil {
+		t.Fatalf("decodeAccessPointClient failed: %s", err)
+	}
+	rssi := int16(-45)
+	rxMode := "HE"
+	rxMCS := uint8(9)
+	txBitrate := uint32(1200000)
+	connectedTime := uint32(42)
+	expected := &AccessPointClient{
+		Address:       "02:00:00:00:00:0a",
+		RSSI:          &rssi,
+		RxMode:        &rxMode,
+		RxMCS:         &rxMCS,
+		TxBitrate:     &txBitrate,
+		ConnectedTime: &connectedTime,
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("decodeAccessPointClient = %+v; want %+v", c, expected)
+	}
+
+	tests := []struct {
+		name string
+		dict map[string]dbus.Variant
+		err  string
+	}{
+		{name: "missing Address", dict: map[string]dbus.Variant{"RSSI": dbus.MakeVariant(int16(-45))}, err: "client diagnostics are missing an Address"},
+		{name: "Address type", dict: map[string]dbus.Variant{"Address": dbus.MakeVariant([]byte{2, 0, 0, 0, 0, 10})}, err: "Address must be of type s; got ay"},
+		{
+			name: "ConnectedTime type",
+			dict: map[string]dbus.Variant{"Address": dbus.MakeVariant("02:00:00:00:00:0a"), "ConnectedTime": dbus.MakeVariant(int64(42))},
+			err:  "client 02:00:00:00:00:0a: ConnectedTime must be of type u; got x",
+		},
+		{
+			name: "RSSI type",
+			dict: map[string]dbus.Variant{"Address": dbus.MakeVariant("02:00:00:00:00:0a"), "RSSI": dbus.MakeVariant(int32(-45))},
+			err:  "client 02:00:00:00:00:0a: RSSI must be of type n; got i",
+		},
+	}
+	for _, tt := range tests {
+		if _, err = decodeAccessPointClient(tt.dict); err == nil || err.Error() != tt.err {
+			t.Errorf("%s: decodeAccessPointClient error = %v; want %q", tt.name, err, tt.err)
+		}
+	}
+}
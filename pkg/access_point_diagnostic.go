@@ -0,0 +1,156 @@
+package spider
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	accessPointDiagnosticInterface            = "net.connman.iwd.AccessPointDiagnostic"
+	accessPointDiagnosticMethodGetDiagnostics = accessPointDiagnosticInterface + ".GetDiagnostics"
+)
+
+var (
+	accessPointDiagnosticLogger *log.Entry
+)
+
+type AccessPointDiagnosticer interface {
+	GetPath() dbus.ObjectPath
+	GetInterface() string
+	GetDiagnostics() ([]*AccessPointClient, error)
+}
+
+type AccessPointClient struct {
+	Address            string
+	RSSI               *int16
+	AverageRSSI        *int16
+	RxMode             *string
+	RxBitrate          *uint32
+	RxMCS              *uint8
+	TxMode             *string
+	TxBitrate          *uint32
+	TxMCS              *uint8
+	InactiveTime       *uint32
+	ExpectedThroughput *uint32
+	ConnectedTime      *uint32
+}
+
+type AccessPointDiagnostic struct {
+	conn *dbus.Conn
+	obj  dbus.BusObject
+	path dbus.ObjectPath
+}
+
+func NewAccessPointDiagnostic(conn *dbus.Conn, path dbus.ObjectPath) (*AccessPointDiagnostic, error) {
+	log.SetReportCaller(true)
+	accessPointDiagnosticLogger = log.WithFields(log.Fields{
+		"type": "AccessPointDiagnostic",
+		"path": path,
+	})
+	obj := conn.Object(IwdService, path)
+	apd := &AccessPointDiagnostic{
+		conn: conn,
+		obj:  obj,
+		path: path,
+	}
+	return apd, nil
+}
+
+func (apd *AccessPointDiagnostic) String() string {
+	return fmt.Sprintf("{Path: %s, Interface: %s}", apd.path, apd.GetInterface())
+}
+
+func (apd *AccessPointDiagnostic) GetPath() dbus.ObjectPath {
+	return apd.path
+}
+
+func (apd *AccessPointDiagnostic) GetInterface() string {
+	return accessPointDiagnosticInterface
+}
+
+func (apd *AccessPointDiagnostic) GetDiagnostics() ([]*AccessPointClient, error) {
+	var dicts []map[string]dbus.Variant
+	if err := apd.obj.Call(accessPointDiagnosticMethodGetDiagnostics, 0).Store(&dicts); err != nil {
+		accessPointDiagnosticLogger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to get diagnostics")
+		return nil, err
+	}
+	clients := make([]*AccessPointClient, 0, len(dicts))
+	for _, dict := range dicts {
+		client, err := decodeAccessPointClient(dict)
+		if err != nil {
+			accessPointDiagnosticLogger.WithFields(log.Fields{
+				"err": err,
+			}).Error("Failed to decode client diagnostics")
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	accessPointDiagnosticLogger.Debugf("Found %d clients", len(clients))
+	return clients, nil
+}
+
+func (c *AccessPointClient) String() string {
+	return fmt.Sprintf("{Address: %s}", c.Address)
+}
+
+func decodeAccessPointClient(dict map[string]dbus.Variant) (*AccessPointClient, error) {
+	address, ok := dict["Address"]
+	if !ok {
+		return nil, fmt.Errorf("client diagnostics are missing an Address")
+	}
+	c := &AccessPointClient{}
+	if err := decodeVariant("Address", address, &c.Address); err != nil {
+		return nil, err
+	}
+	for key, variant := range dict {
+		var value interface{}
+		switch key {
+		case "Address":
+			continue
+		case "RSSI":
+			c.RSSI = new(int16)
+			value = c.RSSI
+		case "AverageRSSI":
+			c.AverageRSSI = new(int16)
+			value = c.AverageRSSI
+		case "RxMode":
+			c.RxMode = new(string)
+			value = c.RxMode
+		case "RxBitrate":
+			c.RxBitrate = new(uint32)
+			value = c.RxBitrate
+		case "RxMCS":
+			c.RxMCS = new(uint8)
+			value = c.RxMCS
+		case "TxMode":
+			c.TxMode = new(string)
+			value = c.TxMode
+		case "TxBitrate":
+			c.TxBitrate = new(uint32)
+			value = c.TxBitrate
+		case "TxMCS":
+			c.TxMCS = new(uint8)
+			value = c.TxMCS
+		case "InactiveTime":
+			c.InactiveTime = new(uint32)
+			value = c.InactiveTime
+		case "ExpectedThroughput":
+			c.ExpectedThroughput = new(uint32)
+			value = c.ExpectedThroughput
+		case "ConnectedTime":
+			c.ConnectedTime = new(uint32)
+			value = c.ConnectedTime
+		default:
+			log.Debugf("Ignoring unknown diagnostic %s for client %s", key, c.Address)
+			continue
+		}
+		if err := decodeVariant(key, variant, value); err != nil {
+			return nil, fmt.Errorf("client %s: %s", c.Address, err)
+		}
+	}
+	return c, nil
+}
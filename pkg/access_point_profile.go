@@ -0,0 +1,358 @@
+package spider
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	AccessPointDirectoryName    = "ap"
+	accessPointProfileExtension = ".ap"
+	profileSectionGeneral       = "General"
+)
+
+type AccessPointProfileSecurity struct {
+	Passphrase   *string
+	PreSharedKey *string
+}
+
+type AccessPointProfileIPv4 struct {
+	Address   *string
+	Gateway   *string
+	Netmask   *string
+	DNSList   []string
+	LeaseTime *uint32
+	IPRange   []string
+}
+
+type AccessPointProfileGeneral struct {
+	Channel   *uint8
+	DisableHT *bool
+}
+
+type AccessPointProfile struct {
+	SSID     string
+	Security AccessPointProfileSecurity
+	IPv4     *AccessPointProfileIPv4
+	General  AccessPointProfileGeneral
+	doc      *iniDocument
+}
+
+func NewAccessPointProfile(ssid string) *AccessPointProfile {
+	return &AccessPointProfile{
+		SSID: ssid,
+	}
+}
+
+func (p *AccessPointProfile) String() string {
+	return fmt.Sprintf("{SSID: %s}", p.SSID)
+}
+
+func (p *AccessPointProfile) FileName() (string, error) {
+	if err := validateAccessPointSSID(p.SSID); err != nil {
+		return "", err
+	}
+	return p.SSID + accessPointProfileExtension, nil
+}
+
+func (p *AccessPointProfile) Validate() error {
+	if _, err := p.FileName(); err != nil {
+		return err
+	}
+	if p.Security.Passphrase == nil && p.Security.PreSharedKey == nil {
+		return fmt.Errorf("%w: access point %s requires a Passphrase or PreSharedKey", ErrInvalidNetworkProfile, p.SSID)
+	}
+	if p.Security.Passphrase != nil {
+		if err := validatePassphrase(*p.Security.Passphrase); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidNetworkProfile, err)
+		}
+	}
+	if p.Security.PreSharedKey != nil {
+		if err := validatePreSharedKey(*p.Security.PreSharedKey); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidNetworkProfile, err)
+		}
+	}
+	if p.IPv4 != nil {
+		for key, value := range map[string]*string{"Address": p.IPv4.Address, "Gateway": p.IPv4.Gateway, "Netmask": p.IPv4.Netmask} {
+			if value == nil {
+				continue
+			}
+			if err := validateIPv4Address(*value); err != nil {
+				return fmt.Errorf("%w: [IPv4] %s: %s", ErrInvalidNetworkProfile, key, err)
+			}
+		}
+		for _, dns := range p.IPv4.DNSList {
+			if err := validateIPv4Address(dns); err != nil {
+				return fmt.Errorf("%w: [IPv4] DNSList: %s", ErrInvalidNetworkProfile, err)
+			}
+		}
+		if err := validateIPv4Range(p.IPv4.IPRange); err != nil {
+			return fmt.Errorf("%w: [IPv4] IPRange: %s", ErrInvalidNetworkProfile, err)
+		}
+	}
+	if p.General.Channel != nil && *p.General.Channel == 0 {
+		return fmt.Errorf("%w: access point %s has an invalid Channel 0", ErrInvalidNetworkProfile, p.SSID)
+	}
+	return nil
+}
+
+func (p *AccessPointProfile) Marshal() ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if p.doc == nil {
+		p.doc = newIniDocument()
+	}
+	d := p.doc
+
+	setOptionalString(d, profileSectionSecurity, "Passphrase", p.Security.Passphrase)
+	setOptionalString(d, profileSectionSecurity, "PreSharedKey", p.Security.PreSharedKey)
+
+	ipv4 := p.IPv4
+	if ipv4 == nil {
+		ipv4 = &AccessPointProfileIPv4{}
+	}
+	setOptionalString(d, profileSectionIPv4, "Address", ipv4.Address)
+	setOptionalString(d, profileSectionIPv4, "Gateway", ipv4.Gateway)
+	setOptionalString(d, profileSectionIPv4, "Netmask", ipv4.Netmask)
+	setOptionalCommaList(d, profileSectionIPv4, "DNSList", ipv4.DNSList)
+	if ipv4.LeaseTime == nil {
+		d.unset(profileSectionIPv4, "LeaseTime")
+	} else {
+		d.set(profileSectionIPv4, "LeaseTime", strconv.FormatUint(uint64(*ipv4.LeaseTime), 10))
+	}
+	setOptionalCommaList(d, profileSectionIPv4, "IPRange", ipv4.IPRange)
+
+	if p.General.Channel == nil {
+		d.unset(profileSectionGeneral, "Channel")
+	} else {
+		d.set(profileSectionGeneral, "Channel", strconv.FormatUint(uint64(*p.General.Channel), 10))
+	}
+	setOptionalBool(d, profileSectionGeneral, "DisableHT", p.General.DisableHT)
+	return []byte(d.String()), nil
+}
+
+func ParseAccessPointProfile(ssid string, data []byte) (*AccessPointProfile, error) {
+	if err := validateAccessPointSSID(ssid); err != nil {
+		log.Errorf("failed to parse access point profile %s: %s", ssid, err)
+		return nil, err
+	}
+	d, err := parseIniDocument(data)
+	if err != nil {
+		log.Errorf("failed to parse access point profile %s: %s", ssid, err)
+		return nil, err
+	}
+	p := NewAccessPointProfile(ssid)
+	p.doc = d
+	for _, section := range d.sections {
+		for _, e := range section.entries {
+			if !e.isKey() {
+				continue
+			}
+			if err = p.parseEntry(section.name, e); err != nil {
+				log.Errorf("failed to parse access point profile %s: %s", ssid, err)
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *AccessPointProfile) parseEntry(section string, e *iniEntry) error {
+	fail := func(format string, args ...interface{}) error {
+		return &IniParseError{Line: e.line, Message: fmt.Sprintf("[%s] %s: %s", section, e.key, fmt.Sprintf(format, args...))}
+	}
+	value := e.value
+	switch section {
+	case profileSectionSecurity:
+		switch e.key {
+		case "Passphrase":
+			if err := validatePassphrase(value); err != nil {
+				return fail("%s", err)
+			}
+			p.Security.Passphrase = &value
+		case "PreSharedKey":
+			if err := validatePreSharedKey(value); err != nil {
+				return fail("%s", err)
+			}
+			p.Security.PreSharedKey = &value
+		}
+	case profileSectionIPv4:
+		if p.IPv4 == nil {
+			p.IPv4 = &AccessPointProfileIPv4{}
+		}
+		switch e.key {
+		case "Address":
+			p.IPv4.Address = &value
+		case "Gateway":
+			p.IPv4.Gateway = &value
+		case "Netmask":
+			p.IPv4.Netmask = &value
+		case "DNSList":
+			p.IPv4.DNSList = splitCommaList(value)
+		case "LeaseTime":
+			leaseTime, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return fail("invalid lease time %q", value)
+			}
+			lt := uint32(leaseTime)
+			p.IPv4.LeaseTime = &lt
+		case "IPRange":
+			p.IPv4.IPRange = splitCommaList(value)
+			if err := validateIPv4Range(p.IPv4.IPRange); err != nil {
+				return fail("%s", err)
+			}
+		}
+	case profileSectionGeneral:
+		switch e.key {
+		case "Channel":
+			channel, err := strconv.ParseUint(value, 10, 8)
+			if err != nil || channel == 0 {
+				return fail("invalid channel %q", value)
+			}
+			c := uint8(channel)
+			p.General.Channel = &c
+		case "DisableHT":
+			b, err := parseProfileBool(value)
+			if err != nil {
+				return fail("%s", err)
+			}
+			p.General.DisableHT = &b
+		}
+	}
+	return nil
+}
+
+func AccessPointDirectory(stateDir string) string {
+	if stateDir == "" {
+		stateDir = DefaultStateDirectory
+	}
+	return filepath.Join(stateDir, AccessPointDirectoryName)
+}
+
+func AccessPointProfilePath(stateDir, ssid string) (string, error) {
+	fileName, err := NewAccessPointProfile(ssid).FileName()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(AccessPointDirectory(stateDir), fileName), nil
+}
+
+func LoadAccessPointProfile(path string) (*AccessPointProfile, error) {
+	base := filepath.Base(path)
+	if filepath.Ext(base) != accessPointProfileExtension {
+		return nil, fmt.Errorf("%w: access point profile %s must have a %s extension", ErrInvalidNetworkProfile, path, accessPointProfileExtension)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Errorf("failed to read access point profile %s: %s", path, err)
+		return nil, err
+	}
+	p, err := ParseAccessPointProfile(strings.TrimSuffix(base, accessPointProfileExtension), data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	log.Debugf("Loaded access point profile %s from %s", p, path)
+	return p, nil
+}
+
+func WriteAccessPointProfile(stateDir string, profile *AccessPointProfile) (string, error) {
+	dir := AccessPointDirectory(stateDir)
+	fileName, err := profile.FileName()
+	if err != nil {
+		log.Errorf("failed to get file name for access point profile %s: %s", profile.SSID, err)
+		return "", err
+	}
+	data, err := profile.Marshal()
+	if err != nil {
+		log.Errorf("failed to marshal access point profile %s: %s", profile.SSID, err)
+		return "", err
+	}
+	if err = os.MkdirAll(dir, networkProfileDirMode); err != nil {
+		log.Errorf("failed to create access point directory %s: %s", dir, err)
+		return "", fmt.Errorf("failed to create access point directory %s: %s", dir, err)
+	}
+	path := filepath.Join(dir, fileName)
+	if err = writeFileAtomic(path, data, networkProfileMode); err != nil {
+		log.Errorf("failed to write access point profile %s: %s", path, err)
+		return "", err
+	}
+	log.Debugf("Wrote access point profile %s to %s", profile.SSID, path)
+	return path, nil
+}
+
+func ListAccessPointProfiles(stateDir string) ([]*AccessPointProfile, error) {
+	dir := AccessPointDirectory(stateDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []*AccessPointProfile{}, nil
+	}
+	if err != nil {
+		log.Errorf("failed to read access point directory %s: %s", dir, err)
+		return nil, err
+	}
+	profiles := make([]*AccessPointProfile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != accessPointProfileExtension {
+			continue
+		}
+		p, err2 := LoadAccessPointProfile(filepath.Join(dir, entry.Name()))
+		if err2 != nil {
+			log.Warnf("Skipping access point profile %s: %s", entry.Name(), err2)
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+func DeleteAccessPointProfile(stateDir, ssid string) error {
+	path, err := AccessPointProfilePath(stateDir, ssid)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil {
+		log.Errorf("failed to delete access point profile %s: %s", path, err)
+		return err
+	}
+	log.Debugf("Deleted access point profile %s", path)
+	return nil
+}
+
+func validateAccessPointSSID(ssid string) error {
+	if len(ssid) == 0 || len(ssid) > maxSSIDLength {
+		return fmt.Errorf("%w: SSID must be between 1 and 32 bytes long", ErrInvalidNetworkProfile)
+	}
+	if ssid == "." || ssid == ".." || strings.ContainsAny(ssid, "/\x00") {
+		return fmt.Errorf("%w: invalid access point SSID %q", ErrInvalidNetworkProfile, ssid)
+	}
+	return nil
+}
+
+func validateIPv4Address(address string) error {
+	if ip := net.ParseIP(address); ip == nil || ip.To4() == nil {
+		return fmt.Errorf("%q is not an IPv4 address", address)
+	}
+	return nil
+}
+
+func validateIPv4Range(ipRange []string) error {
+	if len(ipRange) == 0 {
+		return nil
+	}
+	if len(ipRange) != 2 {
+		return fmt.Errorf("range must be a start and end address; got %d values", len(ipRange))
+	}
+	for _, address := range ipRange {
+		if err := validateIPv4Address(address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package spider
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAccessPointProfileRoundTrip(t *testing.T) {
+	stateDir := t.TempDir()
+	passphrase := "secret123"
+	address := "192.168.80.1"
+	netmask := "255.255.255.0"
+	leaseTime := uint32(3600)
+	channel := uint8(6)
+	disableHT := true
+	profile := NewAccessPointProfile("Hotspot")
+	profile.Security.Passphrase = &passphrase
+	profile.IPv4 = &AccessPointProfileIPv4{
+		Address:   &address,
+		Netmask:   &netmask,
+		DNSList:   []string{"192.168.80.1", "9.9.9.9"},
+		LeaseTime: &leaseTime,
+		IPRange:   []string{"192.168.80.10", "192.168.80.100"},
+	}
+	profile.General.Channel = &channel
+	profile.General.DisableHT = &disableHT
+
+	path, err := WriteAccessPointProfile(stateDir, profile)
+	if err != nil {
+		t.Fatalf("WriteAccessPointProfile failed: %s", err)
+	}
+	if expected := filepath.Join(stateDir, AccessPointDirectoryName, "Hotspot.ap"); path != expected {
+		t.Errorf("path = %s; want %s", path, expected)
+	}
+	loaded, err := LoadAccessPointProfile(path)
+	if err != nil {
+		t.Fatalf("LoadAccessPointProfile failed: %s", err)
+	}
+	loaded.doc = nil
+	profile.doc = nil
+	if !reflect.DeepEqual(loaded, profile) {
+		t.Errorf("loaded profile = %+v; want %+v", loaded, profile)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment := "# keep me\n"
+	if err = os.WriteFile(path, append([]byte(comment), data...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = LoadAccessPointProfile(path)
+	if err != nil {
+		t.Fatalf("LoadAccessPointProfile failed: %s", err)
+	}
+	newPassphrase := "othersecret"
+	loaded.Security.Passphrase = &newPassphrase
+	if _, err = WriteAccessPointProfile(stateDir, loaded); err != nil {
+		t.Fatalf("WriteAccessPointProfile failed: %s", err)
+	}
+	if data, err = os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), comment) || !strings.Contains(string(data), "Passphrase=othersecret") {
+		t.Errorf("rewritten profile lost the comment or the new passphrase:\n%s", data)
+	}
+}
+
+func TestListAndDeleteAccessPointProfiles(t *testing.T) {
+	stateDir := t.TempDir()
+	profiles, err := ListAccessPointProfiles(stateDir)
+	if err != nil {
+		t.Fatalf("ListAccessPointProfiles failed: %s", err)
+	}
+	if len(profiles) != 0 {
+		t.Errorf("listed %d profiles in an empty state directory", len(profiles))
+	}
+
+	passphrase := "secret123"
+	for _, ssid := range []string{"First", "Second"} {
+		profile := NewAccessPointProfile(ssid)
+		profile.Security.Passphrase = &passphrase
+		if _, err = WriteAccessPointProfile(stateDir, profile); err != nil {
+			t.Fatalf("WriteAccessPointProfile failed: %s", err)
+		}
+	}
+	dir := AccessPointDirectory(stateDir)
+	for name, data := range map[string]string{
+		".ap":        "[Security]\nPassphrase=secret123\n",
+		"Broken.ap":  "[General]\nChannel=0\n",
+		"notes.txt":  "not a profile",
+		"Second.psk": "[Security]\nPassphrase=secret123\n",
+	} {
+		if err = os.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profiles, err = ListAccessPointProfiles(stateDir)
+	if err != nil {
+		t.Fatalf("ListAccessPointProfiles failed: %s", err)
+	}
+	ssids := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		ssids = append(ssids, p.SSID)
+	}
+	if expected := []string{"First", "Second"}; !reflect.DeepEqual(ssids, expected) {
+		t.Errorf("listed %v; want %v", ssids, expected)
+	}
+
+	if err = DeleteAccessPointProfile(stateDir, "First"); err != nil {
+		t.Fatalf("DeleteAccessPointProfile failed: %s", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "First.ap")); !os.IsNotExist(err) {
+		t.Errorf("First.ap still exists after delete: %v", err)
+	}
+	if err = DeleteAccessPointProfile(stateDir, "First"); !os.IsNotExist(err) {
+		t.Errorf("deleting a missing profile returned %v; want a not-exist error", err)
+	}
+	if err = DeleteAccessPointProfile(stateDir, ".."); !errors.Is(err, ErrInvalidNetworkProfile) {
+		t.Errorf("deleting profile .. returned %v; want %v", err, ErrInvalidNetworkProfile)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "Second.ap")); err != nil {
+		t.Errorf("Second.ap was removed: %s", err)
+	}
+}
+
+func TestAccessPointProfileInvalidSSID(t *testing.T) {
+	stateDir := t.TempDir()
+	passphrase := "secret123"
+	for _, ssid := range []string{"", ".", "..", "a/b", "nul\x00", strings.Repeat("x", maxSSIDLength+1)} {
+		profile := NewAccessPointProfile(ssid)
+		profile.Security.Passphrase = &passphrase
+		if _, err := WriteAccessPointProfile(stateDir, profile); !errors.Is(err, ErrInvalidNetworkProfile) {
+			t.Errorf("WriteAccessPointProfile(%q) error = %v; want %v", ssid, err, ErrInvalidNetworkProfile)
+		}
+		if _, err := ParseAccessPointProfile(ssid, []byte("[Security]\nPassphrase=secret123\n")); !errors.Is(err, ErrInvalidNetworkProfile) {
+			t.Errorf("ParseAccessPointProfile(%q) error = %v; want %v", ssid, err, ErrInvalidNetworkProfile)
+		}
+		if _, err := AccessPointProfilePath(stateDir, ssid); !errors.Is(err, ErrInvalidNetworkProfile) {
+			t.Errorf("AccessPointProfilePath(%q) error = %v; want %v", ssid, err, ErrInvalidNetworkProfile)
+		}
+	}
+	if _, err := os.Stat(AccessPointDirectory(stateDir)); !os.IsNotExist(err) {
+		t.Errorf("invalid profiles created %s: %v", AccessPointDirectory(stateDir), err)
+	}
+	profile := NewAccessPointProfile(strings.Repeat("x", maxSSIDLength))
+	profile.Security.Passphrase = &passphrase
+	if _, err := WriteAccessPointProfile(stateDir, profile); err != nil {
+		t.Errorf("WriteAccessPointProfile with a %d byte SSID failed: %s", maxSSIDLength, err)
+	}
+}
@@ -5,7 +5,8 @@ import (
 )
 
 const (
-	AgentInterface = "net.connman.iwd.Agent"
+	AgentInterface     = "net.connman.iwd.Agent"
+	AgentErrorCanceled = AgentInterface + ".Error.Canceled"
 )
 
 type AgentClient interface {
@@ -18,3 +19,7 @@ type AgentClient interface {
 	RequestUserPassword(network dbus.ObjectPath, user string) (string, *dbus.Error)
 	Cancel(reason string) *dbus.Error
 }
+
+func NewAgentCanceledError(message string) *dbus.Error {
+	return dbus.NewError(AgentErrorCanceled, []interface{}{message})
+}
@@ -0,0 +1,288 @@
+package spider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	auditLogMode = 0600
+)
+
+type AuditOutcome string
+
+const (
+	AuditOutcomeAnswered AuditOutcome = "answered"
+	AuditOutcomeCanceled AuditOutcome = "canceled"
+	AuditOutcomeError    AuditOutcome = "error"
+)
+
+type AgentAuditRecord struct {
+	Time      time.Time       `json:"time"`
+	Interface string          `json:"interface"`
+	Method    string          `json:"method"`
+	Network   dbus.ObjectPath `json:"network,omitempty"`
+	SSID      string          `json:"ssid,omitempty"`
+	Device    dbus.ObjectPath `json:"device,omitempty"`
+	Sender    string          `json:"sender,omitempty"`
+	Outcome   AuditOutcome    `json:"outcome"`
+	Error     string          `json:"error,omitempty"`
+	Latency   time.Duration   `json:"latency_ns"`
+}
+
+type AuditSink interface {
+	Record(record *AgentAuditRecord) error
+}
+
+type JSONLAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewJSONLAuditSink(path string) (*JSONLAuditSink, error) {
+	logger := log.WithFields(log.Fields{
+		"type": "JSONLAuditSink",
+		"path": path,
+	})
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, auditLogMode)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to open audit log")
+		return nil, fmt.Errorf("failed to open audit log %s: %s", path, err)
+	}
+	return &JSONLAuditSink{
+		file: f,
+	}, nil
+}
+
+func (s *JSONLAuditSink) Record(record *AgentAuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %s", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err = s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %s", err)
+	}
+	return s.file.Sync()
+}
+
+func (s *JSONLAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+type AuditingAgent struct {
+	agent  AgentClient
+	sink   AuditSink
+	sender string
+	logger *log.Entry
+}
+
+func NewAuditingAgent(agent AgentClient, sink AuditSink) *AuditingAgent {
+	return &AuditingAgent{
+		agent: agent,
+		sink:  sink,
+		logger: log.WithFields(log.Fields{
+			"type": "AuditingAgent",
+			"path": agent.GetPath(),
+		}),
+	}
+}
+
+func (a *AuditingAgent) ForSender(sender string) AgentClient {
+	inner := a.agent
+	if scoped, ok := inner.(SenderScopedAgentClient); ok {
+		inner = scoped.ForSender(sender)
+	}
+	return &AuditingAgent{
+		agent:  inner,
+		sink:   a.sink,
+		sender: sender,
+		logger: a.logger.WithFields(log.Fields{
+			"sender": sender,
+		}),
+	}
+}
+
+func (a *AuditingAgent) GetConnection() *dbus.Conn {
+	return a.agent.GetConnection()
+}
+
+func (a *AuditingAgent) GetPath() dbus.ObjectPath {
+	return a.agent.GetPath()
+}
+
+func (a *AuditingAgent) Release() *dbus.Error {
+	start := time.Now()
+	err := a.agent.Release()
+	a.record("Release", "", start, err)
+	return err
+}
+
+func (a *AuditingAgent) RequestPassphrase(network dbus.ObjectPath) (string, *dbus.Error) {
+	start := time.Now()
+	passphrase, err := a.agent.RequestPassphrase(network)
+	a.record("RequestPassphrase", network, start, err)
+	return passphrase, err
+}
+
+func (a *AuditingAgent) RequestPrivateKeyPassphrase(network dbus.ObjectPath) (string, *dbus.Error) {
+	start := time.Now()
+	passphrase, err := a.agent.RequestPrivateKeyPassphrase(network)
+	a.record("RequestPrivateKeyPassphrase", network, start, err)
+	return passphrase, err
+}
+
+func (a *AuditingAgent) RequestUserNameAndPassword(network dbus.ObjectPath) (string, string, *dbus.Error) {
+	start := time.Now()
+	user, password, err := a.agent.RequestUserNameAndPassword(network)
+	a.record("RequestUserNameAndPassword", network, start, err)
+	return user, password, err
+}
+
+func (a *AuditingAgent) RequestUserPassword(network dbus.ObjectPath, user string) (string, *dbus.Error) {
+	start := time.Now()
+	password, err := a.agent.RequestUserPassword(network, user)
+	a.record("RequestUserPassword", network, start, err)
+	return password, err
+}
+
+func (a *AuditingAgent) Cancel(reason string) *dbus.Error {
+	start := time.Now()
+	err := a.agent.Cancel(reason)
+	a.record("Cancel", "", start, err)
+	return err
+}
+
+func (a *AuditingAgent) record(method string, network dbus.ObjectPath, start time.Time, err *dbus.Error) {
+	record := newAgentAuditRecord(AgentInterface, method, a.sender, start, err)
+	record.Network = network
+	if network != "" && a.agent.GetConnection() != nil {
+		if ssid, _, err2 := getNetworkNameAndType(a.agent.GetConnection(), network); err2 == nil {
+			record.SSID = ssid
+		}
+	}
+	writeAgentAuditRecord(a.logger, a.sink, record)
+}
+
+type AuditingNetworkConfigurationAgent struct {
+	agent  NetworkConfigurationAgentClient
+	sink   AuditSink
+	sender string
+	logger *log.Entry
+}
+
+func NewAuditingNetworkConfigurationAgent(agent NetworkConfigurationAgentClient, sink AuditSink) *AuditingNetworkConfigurationAgent {
+	return &AuditingNetworkConfigurationAgent{
+		agent: agent,
+		sink:  sink,
+		logger: log.WithFields(log.Fields{
+			"type": "AuditingNetworkConfigurationAgent",
+			"path": agent.GetPath(),
+		}),
+	}
+}
+
+func (a *AuditingNetworkConfigurationAgent) ForSender(sender string) NetworkConfigurationAgentClient {
+	inner := a.agent
+	if scoped, ok := inner.(SenderScopedNetworkConfigurationAgentClient); ok {
+		inner = scoped.ForSender(sender)
+	}
+	return &AuditingNetworkConfigurationAgent{
+		agent:  inner,
+		sink:   a.sink,
+		sender: sender,
+		logger: a.logger.WithFields(log.Fields{
+			"sender": sender,
+		}),
+	}
+}
+
+func (a *AuditingNetworkConfigurationAgent) GetConnection() *dbus.Conn {
+	return a.agent.GetConnection()
+}
+
+func (a *AuditingNetworkConfigurationAgent) GetPath() dbus.ObjectPath {
+	return a.agent.GetPath()
+}
+
+func (a *AuditingNetworkConfigurationAgent) Release() *dbus.Error {
+	start := time.Now()
+	err := a.agent.Release()
+	a.record("Release", "", start, err)
+	return err
+}
+
+func (a *AuditingNetworkConfigurationAgent) ConfigureIPv4(device dbus.ObjectPath, config map[string]dbus.Variant) *dbus.Error {
+	start := time.Now()
+	err := a.agent.ConfigureIPv4(device, config)
+	a.record("ConfigureIPv4", device, start, err)
+	return err
+}
+
+func (a *AuditingNetworkConfigurationAgent) ConfigureIPv6(device dbus.ObjectPath, config map[string]dbus.Variant) *dbus.Error {
+	start := time.Now()
+	err := a.agent.ConfigureIPv6(device, config)
+	a.record("ConfigureIPv6", device, start, err)
+	return err
+}
+
+func (a *AuditingNetworkConfigurationAgent) CancelIPv4(device dbus.ObjectPath, reason string) *dbus.Error {
+	start := time.Now()
+	err := a.agent.CancelIPv4(device, reason)
+	a.record("CancelIPv4", device, start, err)
+	return err
+}
+
+func (a *AuditingNetworkConfigurationAgent) CancelIPv6(device dbus.ObjectPath, reason string) *dbus.Error {
+	start := time.Now()
+	err := a.agent.CancelIPv6(device, reason)
+	a.record("CancelIPv6", device, start, err)
+	return err
+}
+
+func (a *AuditingNetworkConfigurationAgent) record(method string, device dbus.ObjectPath, start time.Time, err *dbus.Error) {
+	record := newAgentAuditRecord(NetworkConfigurationAgentInterface, method, a.sender, start, err)
+	record.Device = device
+	writeAgentAuditRecord(a.logger, a.sink, record)
+}
+
+func newAgentAuditRecord(iface, method, sender string, start time.Time, err *dbus.Error) *AgentAuditRecord {
+	record := &AgentAuditRecord{
+		Time:      start.UTC(),
+		Interface: iface,
+		Method:    method,
+		Sender:    sender,
+		Outcome:   AuditOutcomeAnswered,
+		Latency:   time.Since(start),
+	}
+	if err != nil {
+		record.Outcome = AuditOutcomeError
+		if err.Name == AgentErrorCanceled {
+			record.Outcome = AuditOutcomeCanceled
+		}
+		record.Error = err.Name
+	} else if strings.HasPrefix(method, "Cancel") {
+		record.Outcome = AuditOutcomeCanceled
+	}
+	return record
+}
+
+func writeAgentAuditRecord(logger *log.Entry, sink AuditSink, record *AgentAuditRecord) {
+	if err := sink.Record(record); err != nil {
+		logger.WithFields(log.Fields{
+			"err": err,
+		}).Errorf("Failed to record audit entry for %s.%s", record.Interface, record.Method)
+	}
+}
@@ -0,0 +1,125 @@
+package spider
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+type memoryAuditSink struct {
+	records []*AgentAuditRecord
+}
+
+func (s *memoryAuditSink) Record(record *AgentAuditRecord) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestAuditingAgentOutcomes(t *testing.T) {
+	sink := &memoryAuditSink{}
+	agent := NewAuditingAgent(newScopedAgent(nil, testNetworkPath, Credentials{Passphrase: "password"}), sink).ForSender(testIwdOwner)
+	if _, err := agent.RequestPassphrase(testNetworkPath); err != nil {
+		t.Fatalf("RequestPassphrase failed: %s", err)
+	}
+	if _, err := agent.RequestPrivateKeyPassphrase(testNetworkPath); err == nil {
+		t.Fatalf("RequestPrivateKeyPassphrase without one succeeded")
+	}
+	if err := agent.Cancel("user-canceled"); err != nil {
+		t.Fatalf("Cancel failed: %s", err)
+	}
+	expected := []struct {
+		method  string
+		outcome AuditOutcome
+		err     string
+	}{
+		{method: "RequestPassphrase", outcome: AuditOutcomeAnswered},
+		{method: "RequestPrivateKeyPassphrase", outcome: AuditOutcomeCanceled, err: AgentErrorCanceled},
+		{method: "Cancel", outcome: AuditOutcomeCanceled},
+	}
+	if len(sink.records) != len(expected) {
+		t.Fatalf("recorded %d entries; want %d", len(sink.records), len(expected))
+	}
+	for i, e := range expected {
+		record := sink.records[i]
+		if record.Method != e.method || record.Outcome != e.outcome || record.Error != e.err || record.Sender != testIwdOwner || record.Interface != AgentInterface {
+			t.Errorf("record %d = %+v; want %s with outcome %s and error %q", i, record, e.method, e.outcome, e.err)
+		}
+	}
+	if sink.records[0].Network != testNetworkPath {
+		t.Errorf("RequestPassphrase record Network = %s; want %s", sink.records[0].Network, testNetworkPath)
+	}
+}
+
+func TestAuditingNetworkConfigurationAgentCancel(t *testing.T) {
+	sink := &memoryAuditSink{}
+	agent := NewAuditingNetworkConfigurationAgent(NewBaseNetworkConfigurationAgent(nil, NetworkConfigurationCallbacks{}), sink)
+	_ = agent.CancelIPv4(testDevicePath, "timeout")
+	_ = agent.CancelIPv6(testDevicePath, "timeout")
+	if len(sink.records) != 2 {
+		t.Fatalf("recorded %d entries; want 2", len(sink.records))
+	}
+	for _, record := range sink.records {
+		if record.Outcome != AuditOutcomeCanceled || record.Device != testDevicePath {
+			t.Errorf("record = %+v; want outcome %s for %s", record, AuditOutcomeCanceled, testDevicePath)
+		}
+	}
+}
+
+func TestJSONLAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewJSONLAuditSink(path)
+	if err != nil {
+		t.Fatalf("NewJSONLAuditSink failed: %s", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != auditLogMode {
+		t.Errorf("audit log mode = %o; want %o", info.Mode().Perm(), auditLogMode)
+	}
+	credentials := Credentials{
+		Passphrase:           "wifi passphrase",
+		User:                 "alice@example.com",
+		Password:             "hunter2 password",
+		PrivateKeyPassphrase: "private key passphrase",
+	}
+	agent := NewAuditingAgent(newScopedAgent(nil, testNetworkPath, credentials), sink).ForSender(testIwdOwner)
+	_, _ = agent.RequestPassphrase(testNetworkPath)
+	_, _ = agent.RequestPrivateKeyPassphrase(testNetworkPath)
+	_, _, _ = agent.RequestUserNameAndPassword(testNetworkPath)
+	_, _ = agent.RequestUserPassword(testNetworkPath, credentials.User)
+	_, _ = agent.RequestPassphrase(dbus.ObjectPath("/net/connman/iwd/0/3/4f6666696365_psk"))
+	_ = agent.Cancel("user-canceled")
+	_ = agent.Release()
+	if err = sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{credentials.Passphrase, credentials.User, credentials.Password, credentials.PrivateKeyPassphrase} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("audit log contains secret %q:\n%s", secret, data)
+		}
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	lines := 0
+	for scanner.Scan() {
+		var record AgentAuditRecord
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Errorf("audit line %d is not a record: %s", lines+1, err)
+		}
+		lines++
+	}
+	if lines != 7 {
+		t.Errorf("audit log has %d records; want 7", lines)
+	}
+}
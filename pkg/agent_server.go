@@ -0,0 +1,388 @@
+package spider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	AgentServerPathPrefix = "/spider/agent"
+	introspectInterface   = "org.freedesktop.DBus.Introspectable"
+)
+
+var (
+	agentServerCounter atomic.Uint64
+
+	agentIntrospection = introspect.Interface{
+		Name: AgentInterface,
+		Methods: []introspect.Method{
+			{Name: "Release"},
+			{Name: "RequestPassphrase", Args: []introspect.Arg{inArg("network", "o"), outArg("passphrase", "s")}},
+			{Name: "RequestPrivateKeyPassphrase", Args: []introspect.Arg{inArg("network", "o"), outArg("passphrase", "s")}},
+			{Name: "RequestUserNameAndPassword", Args: []introspect.Arg{inArg("network", "o"), outArg("user", "s"), outArg("password", "s")}},
+			{Name: "RequestUserPassword", Args: []introspect.Arg{inArg("network", "o"), inArg("user", "s"), outArg("password", "s")}},
+			{Name: "Cancel", Args: []introspect.Arg{inArg("reason", "s")}},
+		},
+	}
+	networkConfigurationAgentIntrospection = introspect.Interface{
+		Name: NetworkConfigurationAgentInterface,
+		Methods: []introspect.Method{
+			{Name: "Release"},
+			{Name: "ConfigureIPv4", Args: []introspect.Arg{inArg("device", "o"), inArg("config", "a{sv}")}},
+			{Name: "ConfigureIPv6", Args: []introspect.Arg{inArg("device", "o"), inArg("config", "a{sv}")}},
+			{Name: "CancelIPv4", Args: []introspect.Arg{inArg("device", "o"), inArg("reason", "s")}},
+			{Name: "CancelIPv6", Args: []introspect.Arg{inArg("device", "o"), inArg("reason", "s")}},
+		},
+	}
+	signalLevelAgentIntrospection = introspect.Interface{
+		Name: SignalLevelAgentInterface,
+		Methods: []introspect.Method{
+			{Name: "Release", Args: []introspect.Arg{inArg("device", "o")}},
+			{Name: "Changed", Args: []introspect.Arg{inArg("device", "o"), inArg("level", "y")}},
+		},
+	}
+)
+
+type SenderScopedAgentClient interface {
+	AgentClient
+	ForSender(sender string) AgentClient
+}
+
+type SenderScopedNetworkConfigurationAgentClient interface {
+	NetworkConfigurationAgentClient
+	ForSender(sender string) NetworkConfigurationAgentClient
+}
+
+type AgentServer struct {
+	conn       *dbus.Conn
+	path       dbus.ObjectPath
+	iface      string
+	unregister func() error
+	owner      *IwdOwnerTracker
+	released   atomic.Bool
+	done       chan struct{}
+	once       sync.Once
+	err        error
+	logger     *log.Entry
+}
+
+type servedAgent struct {
+	AgentClient
+	path dbus.ObjectPath
+}
+
+func (a *servedAgent) GetPath() dbus.ObjectPath {
+	return a.path
+}
+
+type servedNetworkConfigurationAgent struct {
+	NetworkConfigurationAgentClient
+	path dbus.ObjectPath
+}
+
+func (a *servedNetworkConfigurationAgent) GetPath() dbus.ObjectPath {
+	return a.path
+}
+
+type servedSignalLevelAgent struct {
+	SignalLevelAgentClient
+	path dbus.ObjectPath
+}
+
+func (a *servedSignalLevelAgent) GetPath() dbus.ObjectPath {
+	return a.path
+}
+
+func ServeAgent(ctx context.Context, conn *dbus.Conn, agent AgentClient) (*AgentServer, error) {
+	s, err := newAgentServer(conn, agent.GetPath(), AgentInterface)
+	if err != nil {
+		return nil, err
+	}
+	served := &servedAgent{AgentClient: agent, path: s.path}
+	forSender := func(sender dbus.Sender) AgentClient {
+		if scoped, ok := agent.(SenderScopedAgentClient); ok {
+			return scoped.ForSender(string(sender))
+		}
+		return agent
+	}
+	methods := map[string]interface{}{
+		"Release": func(sender dbus.Sender) *dbus.Error {
+			if err := s.verify(sender, "Release"); err != nil {
+				return err
+			}
+			err := forSender(sender).Release()
+			s.release()
+			return err
+		},
+		"RequestPassphrase": func(sender dbus.Sender, network dbus.ObjectPath) (string, *dbus.Error) {
+			if err := s.verify(sender, "RequestPassphrase"); err != nil {
+				return "", err
+			}
+			return forSender(sender).RequestPassphrase(network)
+		},
+		"RequestPrivateKeyPassphrase": func(sender dbus.Sender, network dbus.ObjectPath) (string, *dbus.Error) {
+			if err := s.verify(sender, "RequestPrivateKeyPassphrase"); err != nil {
+				return "", err
+			}
+			return forSender(sender).RequestPrivateKeyPassphrase(network)
+		},
+		"RequestUserNameAndPassword": func(sender dbus.Sender, network dbus.ObjectPath) (string, string, *dbus.Error) {
+			if err := s.verify(sender, "RequestUserNameAndPassword"); err != nil {
+				return "", "", err
+			}
+			return forSender(sender).RequestUserNameAndPassword(network)
+		},
+		"RequestUserPassword": func(sender dbus.Sender, network dbus.ObjectPath, user string) (string, *dbus.Error) {
+			if err := s.verify(sender, "RequestUserPassword"); err != nil {
+				return "", err
+			}
+			return forSender(sender).RequestUserPassword(network, user)
+		},
+		"Cancel": func(sender dbus.Sender, reason string) *dbus.Error {
+			if err := s.verify(sender, "Cancel"); err != nil {
+				return err
+			}
+			return forSender(sender).Cancel(reason)
+		},
+	}
+	if err = s.export(methods, agentIntrospection); err != nil {
+		return nil, err
+	}
+	am, err := GetAgentManager(conn)
+	if err != nil {
+		s.abandon()
+		return nil, err
+	}
+	if err = am.RegisterAgent(served); err != nil {
+		s.abandon()
+		return nil, err
+	}
+	s.unregister = func() error {
+		return am.UnregisterAgent(served)
+	}
+	s.start(ctx)
+	return s, nil
+}
+
+func ServeNetworkConfigurationAgent(ctx context.Context, conn *dbus.Conn, agent NetworkConfigurationAgentClient) (*AgentServer, error) {
+	s, err := newAgentServer(conn, agent.GetPath(), NetworkConfigurationAgentInterface)
+	if err != nil {
+		return nil, err
+	}
+	served := &servedNetworkConfigurationAgent{NetworkConfigurationAgentClient: agent, path: s.path}
+	forSender := func(sender dbus.Sender) NetworkConfigurationAgentClient {
+		if scoped, ok := agent.(SenderScopedNetworkConfigurationAgentClient); ok {
+			return scoped.ForSender(string(sender))
+		}
+		return agent
+	}
+	methods := map[string]interface{}{
+		"Release": func(sender dbus.Sender) *dbus.Error {
+			if err := s.verify(sender, "Release"); err != nil {
+				return err
+			}
+			err := forSender(sender).Release()
+			s.release()
+			return err
+		},
+		"ConfigureIPv4": func(sender dbus.Sender, device dbus.ObjectPath, config map[string]dbus.Variant) *dbus.Error {
+			if err := s.verify(sender, "ConfigureIPv4"); err != nil {
+				return err
+			}
+			return forSender(sender).ConfigureIPv4(device, config)
+		},
+		"ConfigureIPv6": func(sender dbus.Sender, device dbus.ObjectPath, config map[string]dbus.Variant) *dbus.Error {
+			if err := s.verify(sender, "ConfigureIPv6"); err != nil {
+				return err
+			}
+			return forSender(sender).ConfigureIPv6(device, config)
+		},
+		"CancelIPv4": func(sender dbus.Sender, device dbus.ObjectPath, reason string) *dbus.Error {
+			if err := s.verify(sender, "CancelIPv4"); err != nil {
+				return err
+			}
+			return forSender(sender).CancelIPv4(device, reason)
+		},
+		"CancelIPv6": func(sender dbus.Sender, device dbus.ObjectPath, reason string) *dbus.Error {
+			if err := s.verify(sender, "CancelIPv6"); err != nil {
+				return err
+			}
+			return forSender(sender).CancelIPv6(device, reason)
+		},
+	}
+	if err = s.export(methods, networkConfigurationAgentIntrospection); err != nil {
+		return nil, err
+	}
+	am, err := GetAgentManager(conn)
+	if err != nil {
+		s.abandon()
+		return nil, err
+	}
+	if err = am.RegisterNetworkConfigurationAgent(served); err != nil {
+		s.abandon()
+		return nil, err
+	}
+	s.unregister = func() error {
+		return am.UnregisterNetworkConfigurationAgent(served)
+	}
+	s.start(ctx)
+	return s, nil
+}
+
+func ServeSignalLevelAgent(ctx context.Context, conn *dbus.Conn, station *Station, agent SignalLevelAgentClient, levels []int16) (*AgentServer, error) {
+	s, err := newAgentServer(conn, agent.GetPath(), SignalLevelAgentInterface)
+	if err != nil {
+		return nil, err
+	}
+	served := &servedSignalLevelAgent{SignalLevelAgentClient: agent, path: s.path}
+	methods := map[string]interface{}{
+		"Release": func(sender dbus.Sender, device dbus.ObjectPath) *dbus.Error {
+			if err := s.verify(sender, "Release"); err != nil {
+				return err
+			}
+			err := agent.Release(device)
+			s.release()
+			return err
+		},
+		"Changed": func(sender dbus.Sender, device dbus.ObjectPath, level uint8) *dbus.Error {
+			if err := s.verify(sender, "Changed"); err != nil {
+				return err
+			}
+			return agent.Changed(device, level)
+		},
+	}
+	if err = s.export(methods, signalLevelAgentIntrospection); err != nil {
+		return nil, err
+	}
+	if err = station.RegisterSignalLevelAgent(served, levels); err != nil {
+		s.abandon()
+		return nil, err
+	}
+	s.unregister = func() error {
+		return station.UnregisterSignalLevelAgent(served)
+	}
+	s.start(ctx)
+	return s, nil
+}
+
+func newAgentServer(conn *dbus.Conn, base dbus.ObjectPath, iface string) (*AgentServer, error) {
+	owner, err := NewIwdOwnerTracker(conn)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSuffix(string(base), "/")
+	if !base.IsValid() || prefix == "" {
+		prefix = AgentServerPathPrefix
+	}
+	path := dbus.ObjectPath(fmt.Sprintf("%s/%d", prefix, agentServerCounter.Add(1)))
+	return &AgentServer{
+		conn:  conn,
+		path:  path,
+		iface: iface,
+		owner: owner,
+		done:  make(chan struct{}),
+		logger: log.WithFields(log.Fields{
+			"type":      "AgentServer",
+			"path":      path,
+			"interface": iface,
+		}),
+	}, nil
+}
+
+func (s *AgentServer) GetPath() dbus.ObjectPath {
+	return s.path
+}
+
+func (s *AgentServer) GetInterface() string {
+	return s.iface
+}
+
+func (s *AgentServer) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *AgentServer) String() string {
+	return fmt.Sprintf("{Path: %s, Interface: %s}", s.path, s.iface)
+}
+
+func (s *AgentServer) Close() error {
+	s.once.Do(func() {
+		if !s.released.Load() && s.unregister != nil {
+			s.err = s.unregister()
+		}
+		s.unexport()
+		s.owner.Close()
+		close(s.done)
+		s.logger.Debugf("Stopped serving agent")
+	})
+	return s.err
+}
+
+func (s *AgentServer) export(methods map[string]interface{}, iface introspect.Interface) error {
+	if err := s.conn.ExportMethodTable(methods, s.path, s.iface); err != nil {
+		s.owner.Close()
+		s.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("failed to export agent")
+		return fmt.Errorf("failed to export agent %s: %s", s.path, err)
+	}
+	node := &introspect.Node{
+		Name:       string(s.path),
+		Interfaces: []introspect.Interface{introspect.IntrospectData, iface},
+	}
+	if err := s.conn.Export(introspect.NewIntrospectable(node), s.path, introspectInterface); err != nil {
+		s.abandon()
+		s.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("failed to export agent introspection data")
+		return fmt.Errorf("failed to export introspection data for agent %s: %s", s.path, err)
+	}
+	return nil
+}
+
+func (s *AgentServer) unexport() {
+	_ = s.conn.Export(nil, s.path, s.iface)
+	_ = s.conn.Export(nil, s.path, introspectInterface)
+}
+
+func (s *AgentServer) abandon() {
+	s.unexport()
+	s.owner.Close()
+}
+
+func (s *AgentServer) verify(sender dbus.Sender, method string) *dbus.Error {
+	return s.owner.Verify(sender, s.iface+"."+method)
+}
+
+func (s *AgentServer) release() {
+	s.released.Store(true)
+	go func() {
+		_ = s.Close()
+	}()
+}
+
+func (s *AgentServer) start(ctx context.Context) {
+	s.logger.Debugf("Serving agent")
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = s.Close()
+		case <-s.done:
+		}
+	}()
+}
+
+func inArg(name, signature string) introspect.Arg {
+	return introspect.Arg{Name: name, Type: signature, Direction: "in"}
+}
+
+func outArg(name, signature string) introspect.Arg {
+	return introspect.Arg{Name: name, Type: signature, Direction: "out"}
+}
@@ -0,0 +1,138 @@
+package spider
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	testIwdOwner         = ":1.1"
+	testAgentManagerPath = dbus.ObjectPath("/net/connman/iwd")
+
+	testUnknownInterfaceError = "org.freedesktop.DBus.Error.UnknownInterface"
+)
+
+type testAgentManager struct {
+	mu           sync.Mutex
+	registered   []dbus.ObjectPath
+	unregistered []dbus.ObjectPath
+}
+
+func exportTestAgentManager(t *testing.T, conn *dbus.Conn, objects testObjects) (*testBus, *testAgentManager) {
+	t.Helper()
+	bus := exportTestBus(t, conn)
+	bus.setOwner(t, IwdService, testIwdOwner)
+	if objects == nil {
+		objects = testObjects{}
+	}
+	objects[testAgentManagerPath] = map[string]map[string]interface{}{agentManagerInterface: {}}
+	exportTestObjects(t, conn, objects)
+	am := &testAgentManager{}
+	if err := conn.ExportMethodTable(map[string]interface{}{
+		"RegisterAgent": func(path dbus.ObjectPath) *dbus.Error {
+			am.mu.Lock()
+			defer am.mu.Unlock()
+			am.registered = append(am.registered, path)
+			return nil
+		},
+		"UnregisterAgent": func(path dbus.ObjectPath) *dbus.Error {
+			am.mu.Lock()
+			defer am.mu.Unlock()
+			am.unregistered = append(am.unregistered, path)
+			return nil
+		},
+	}, testAgentManagerPath, agentManagerInterface); err != nil {
+		t.Fatal(err)
+	}
+	return bus, am
+}
+
+func (am *testAgentManager) getRegistered() []dbus.ObjectPath {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	return slices.Clone(am.registered)
+}
+
+func (am *testAgentManager) getUnregistered() []dbus.ObjectPath {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	return slices.Clone(am.unregistered)
+}
+
+func serveTestAgent(t *testing.T, conn *dbus.Conn, agent AgentClient) (*AgentServer, context.CancelFunc) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := ServeAgent(ctx, conn, agent)
+	if err != nil {
+		cancel()
+		t.Fatalf("ServeAgent failed: %s", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		_ = s.Close()
+	})
+	return s, cancel
+}
+
+func waitForTestAgentServer(t *testing.T, s *AgentServer) {
+	t.Helper()
+	select {
+	case <-s.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for agent server %s to stop", s.GetPath())
+	}
+}
+
+func TestServeAgentUniquePaths(t *testing.T) {
+	client, server := newTestConnPair(t)
+	_, am := exportTestAgentManager(t, server, nil)
+	agent := newScopedAgent(client, testNetworkPath, Credentials{Passphrase: "password"})
+	first, _ := serveTestAgent(t, client, agent)
+	second, _ := serveTestAgent(t, client, agent)
+	if first.GetPath() == second.GetPath() {
+		t.Fatalf("both agent servers were exported at %s", first.GetPath())
+	}
+	for _, s := range []*AgentServer{first, second} {
+		if !s.GetPath().IsValid() || !slices.Contains(am.getRegistered(), s.GetPath()) {
+			t.Errorf("agent server path %s was not registered; registered %v", s.GetPath(), am.getRegistered())
+		}
+		call := callTestAs(t, server, testIwdOwner, s.GetPath(), AgentInterface+".RequestPassphrase", testNetworkPath)
+		var passphrase string
+		if err := call.Store(&passphrase); err != nil || passphrase != "password" {
+			t.Errorf("RequestPassphrase on %s = %q, %v; want password", s.GetPath(), passphrase, err)
+		}
+	}
+}
+
+func TestServeAgentStopsOnContextCancel(t *testing.T) {
+	client, server := newTestConnPair(t)
+	_, am := exportTestAgentManager(t, server, nil)
+	s, cancel := serveTestAgent(t, client, newScopedAgent(client, testNetworkPath, Credentials{Passphrase: "password"}))
+	cancel()
+	waitForTestAgentServer(t, s)
+	if unregistered := am.getUnregistered(); !slices.Equal(unregistered, []dbus.ObjectPath{s.GetPath()}) {
+		t.Errorf("unregistered %v; want %s", unregistered, s.GetPath())
+	}
+	call := callTestAs(t, server, testIwdOwner, s.GetPath(), AgentInterface+".RequestPassphrase", testNetworkPath)
+	assertTestDBusError(t, "RequestPassphrase after cancel", call.Err, testUnknownInterfaceError)
+}
+
+func TestServeAgentStopsOnRelease(t *testing.T) {
+	client, server := newTestConnPair(t)
+	_, am := exportTestAgentManager(t, server, nil)
+	s, _ := serveTestAgent(t, client, newScopedAgent(client, testNetworkPath, Credentials{Passphrase: "password"}))
+	if call := callTestAs(t, server, testIwdOwner, s.GetPath(), AgentInterface+".Release"); call.Err != nil {
+		t.Fatalf("Release failed: %s", call.Err)
+	}
+	waitForTestAgentServer(t, s)
+	if unregistered := am.getUnregistered(); len(unregistered) != 0 {
+		t.Errorf("released agent was unregistered again: %v", unregistered)
+	}
+	call := callTestAs(t, server, testIwdOwner, s.GetPath(), AgentInterface+".RequestPassphrase", testNetworkPath)
+	assertTestDBusError(t, "RequestPassphrase after Release", call.Err, testUnknownInterfaceError)
+}
@@ -0,0 +1,100 @@
+package spider
+
+import (
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	BaseNetworkConfigurationAgentPath = "/spider/networkconfigurationagent"
+	dbusErrorInvalidArgs              = "org.freedesktop.DBus.Error.InvalidArgs"
+)
+
+type NetworkConfigurationCallbacks struct {
+	ConfigureIPv4 func(device dbus.ObjectPath, config *NetworkConfigurationConfig) error
+	ConfigureIPv6 func(device dbus.ObjectPath, config *NetworkConfigurationConfig) error
+	CancelIPv4    func(device dbus.ObjectPath, reason string)
+	CancelIPv6    func(device dbus.ObjectPath, reason string)
+	Release       func()
+}
+
+type BaseNetworkConfigurationAgent struct {
+	conn      *dbus.Conn
+	path      dbus.ObjectPath
+	callbacks NetworkConfigurationCallbacks
+	logger    *log.Entry
+}
+
+func NewBaseNetworkConfigurationAgent(conn *dbus.Conn, callbacks NetworkConfigurationCallbacks) *BaseNetworkConfigurationAgent {
+	log.SetReportCaller(true)
+	return &BaseNetworkConfigurationAgent{
+		conn:      conn,
+		path:      BaseNetworkConfigurationAgentPath,
+		callbacks: callbacks,
+		logger: log.WithFields(log.Fields{
+			"type": "BaseNetworkConfigurationAgent",
+			"path": BaseNetworkConfigurationAgentPath,
+		}),
+	}
+}
+
+func (a *BaseNetworkConfigurationAgent) GetConnection() *dbus.Conn {
+	return a.conn
+}
+
+func (a *BaseNetworkConfigurationAgent) GetPath() dbus.ObjectPath {
+	return a.path
+}
+
+func (a *BaseNetworkConfigurationAgent) Release() *dbus.Error {
+	a.logger.Infof("Released NetworkConfigurationAgent")
+	if a.callbacks.Release != nil {
+		a.callbacks.Release()
+	}
+	return nil
+}
+
+func (a *BaseNetworkConfigurationAgent) ConfigureIPv4(device dbus.ObjectPath, config map[string]dbus.Variant) *dbus.Error {
+	return a.configure("IPv4", device, config, a.callbacks.ConfigureIPv4)
+}
+
+func (a *BaseNetworkConfigurationAgent) ConfigureIPv6(device dbus.ObjectPath, config map[string]dbus.Variant) *dbus.Error {
+	return a.configure("IPv6", device, config, a.callbacks.ConfigureIPv6)
+}
+
+func (a *BaseNetworkConfigurationAgent) CancelIPv4(device dbus.ObjectPath, reason string) *dbus.Error {
+	a.logger.Infof("Canceling IPv4 configuration of Device %s because: %s", device, reason)
+	if a.callbacks.CancelIPv4 != nil {
+		a.callbacks.CancelIPv4(device, reason)
+	}
+	return nil
+}
+
+func (a *BaseNetworkConfigurationAgent) CancelIPv6(device dbus.ObjectPath, reason string) *dbus.Error {
+	a.logger.Infof("Canceling IPv6 configuration of Device %s because: %s", device, reason)
+	if a.callbacks.CancelIPv6 != nil {
+		a.callbacks.CancelIPv6(device, reason)
+	}
+	return nil
+}
+
+func (a *BaseNetworkConfigurationAgent) configure(family string, device dbus.ObjectPath, config map[string]dbus.Variant, callback func(dbus.ObjectPath, *NetworkConfigurationConfig) error) *dbus.Error {
+	c, err := DecodeNetworkConfigurationConfig(config)
+	if err != nil {
+		a.logger.WithFields(log.Fields{
+			"err": err,
+		}).Errorf("failed to decode %s configuration for Device %s", family, device)
+		return dbus.NewError(dbusErrorInvalidArgs, []interface{}{err.Error()})
+	}
+	a.logger.Infof("Configuring %s on Device %s with method %s", family, device, c.Method)
+	if callback == nil {
+		return nil
+	}
+	if err = callback(device, c); err != nil {
+		a.logger.WithFields(log.Fields{
+			"err": err,
+		}).Errorf("failed to apply %s configuration for Device %s", family, device)
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
@@ -20,13 +20,21 @@ type BasicServiceSetter interface {
 	GetPath() dbus.ObjectPath
 	GetInterface() string
 	GetAddress() string
+	GetFrequency() *uint32
+	GetBand() string
+	GetChannel() *uint32
+	GetSignalStrength() *int16
+	GetConnected() bool
 }
 
 type BasicServiceSet struct {
-	conn    *dbus.Conn
-	obj     dbus.BusObject
-	path    dbus.ObjectPath
-	address string
+	conn           *dbus.Conn
+	obj            dbus.BusObject
+	path           dbus.ObjectPath
+	address        string
+	frequency      *uint32
+	signalStrength *int16
+	connected      bool
 }
 
 func NewBasicServiceSet(conn *dbus.Conn, path dbus.ObjectPath) (*BasicServiceSet, error) {
@@ -70,6 +78,86 @@ func (bss *BasicServiceSet) GetAddress() string {
 	return bss.address
 }
 
+func (bss *BasicServiceSet) GetFrequency() *uint32 {
+	return bss.frequency
+}
+
+func (bss *BasicServiceSet) GetBand() string {
+	if bss.frequency == nil {
+		return ""
+	}
+	return frequencyToBand(*bss.frequency)
+}
+
+func (bss *BasicServiceSet) GetChannel() *uint32 {
+	if bss.frequency == nil {
+		return nil
+	}
+	channel := frequencyToChannel(*bss.frequency)
+	if channel == 0 {
+		return nil
+	}
+	return &channel
+}
+
+func (bss *BasicServiceSet) GetSignalStrength() *int16 {
+	return bss.signalStrength
+}
+
+func (bss *BasicServiceSet) GetConnected() bool {
+	return bss.connected
+}
+
 func (bss *BasicServiceSet) String() string {
-	return fmt.Sprintf("{Path: %s, Interface: %s, Address: %s}", bss.path, bss.GetInterface(), bss.address)
+	var frequency string
+	var channel string
+	var signalStrength string
+	if bss.frequency == nil {
+		frequency = "<nil>"
+	} else {
+		frequency = fmt.Sprintf("%d", *bss.frequency)
+	}
+	if c := bss.GetChannel(); c == nil {
+		channel = "<nil>"
+	} else {
+		channel = fmt.Sprintf("%d", *c)
+	}
+	if bss.signalStrength == nil {
+		signalStrength = "<nil>"
+	} else {
+		signalStrength = fmt.Sprintf("%d", *bss.signalStrength)
+	}
+	return fmt.Sprintf("{Path: %s, Interface: %s, Address: %s, Frequency: %s, Band: %s, Channel: %s, SignalStrength: %s, Connected: %t}", bss.path, bss.GetInterface(), bss.address, frequency, bss.GetBand(), channel, signalStrength, bss.connected)
+}
+
+func frequencyToBand(frequency uint32) string {
+	switch {
+	case frequency >= 2400 && frequency < 2500:
+		return "2.4 GHz"
+	case frequency >= 5925 && frequency < 7125:
+		return "6 GHz"
+	case frequency >= 4900 && frequency < 5925:
+		return "5 GHz"
+	default:
+		return ""
+	}
+}
+
+func frequencyToChannel(frequency uint32) uint32 {
+	switch {
+	case frequency == 2484:
+		return 14
+	case frequency >= 2412 && frequency < 2484:
+		return (frequency - 2407) / 5
+	case frequency == 5935:
+		return 2
+	case frequency >= 5950 && frequency < 7125:
+		return (frequency - 5950) / 5
+	case frequency >= 5000 && frequency < 5925:
+		return (frequency - 5000) / 5
+	case frequency >= 4900 && frequency < 5000:
+		return (frequency - 4000) / 5
+	default:
+		return 0
+	}
 }
@@ -0,0 +1,53 @@
+package spider
+
+import (
+	"testing"
+)
+
+func TestFrequencyToBandAndChannel(t *testing.T) {
+	tests := []struct {
+		frequency uint32
+		band      string
+		channel   uint32
+	}{
+		{frequency: 2412, band: "2.4 GHz", channel: 1},
+		{frequency: 2437, band: "2.4 GHz", channel: 6},
+		{frequency: 2472, band: "2.4 GHz", channel: 13},
+		{frequency: 2484, band: "2.4 GHz", channel: 14},
+		{frequency: 4920, band: "5 GHz", channel: 184},
+		{frequency: 5180, band: "5 GHz", channel: 36},
+		{frequency: 5825, band: "5 GHz", channel: 165},
+		{frequency: 5885, band: "5 GHz", channel: 177},
+		{frequency: 5935, band: "6 GHz", channel: 2},
+		{frequency: 5955, band: "6 GHz", channel: 1},
+		{frequency: 6415, band: "6 GHz", channel: 93},
+		{frequency: 7115, band: "6 GHz", channel: 233},
+		{frequency: 2399, band: "", channel: 0},
+		{frequency: 7125, band: "", channel: 0},
+		{frequency: 60480, band: "", channel: 0},
+	}
+	for _, tt := range tests {
+		if band := frequencyToBand(tt.frequency); band != tt.band {
+			t.Errorf("frequencyToBand(%d) = %q; want %q", tt.frequency, band, tt.band)
+		}
+		if channel := frequencyToChannel(tt.frequency); channel != tt.channel {
+			t.Errorf("frequencyToChannel(%d) = %d; want %d", tt.frequency, channel, tt.channel)
+		}
+	}
+}
+
+func TestBasicServiceSetChannel(t *testing.T) {
+	bss := &BasicServiceSet{}
+	if channel := bss.GetChannel(); channel != nil {
+		t.Errorf("GetChannel without a frequency = %d; want nil", *channel)
+	}
+	frequency := uint32(2484)
+	bss.frequency = &frequency
+	if channel := bss.GetChannel(); channel == nil || *channel != 14 {
+		t.Errorf("GetChannel for %d MHz = %v; want 14", frequency, channel)
+	}
+	frequency = 60480
+	if channel := bss.GetChannel(); channel != nil {
+		t.Errorf("GetChannel for %d MHz = %d; want nil", frequency, *channel)
+	}
+}
@@ -0,0 +1,184 @@
+package spider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+type AgentRequestHandler interface {
+	RequestPassphrase(ctx context.Context, network dbus.ObjectPath) (string, error)
+	RequestPrivateKeyPassphrase(ctx context.Context, network dbus.ObjectPath) (string, error)
+	RequestUserNameAndPassword(ctx context.Context, network dbus.ObjectPath) (string, string, error)
+	RequestUserPassword(ctx context.Context, network dbus.ObjectPath, user string) (string, error)
+}
+
+type AgentCancelError struct {
+	Reason string
+}
+
+func (e *AgentCancelError) Error() string {
+	return fmt.Sprintf("agent request canceled: %s", e.Reason)
+}
+
+type AgentRequest struct {
+	Network dbus.ObjectPath
+	Method  string
+	Started time.Time
+}
+
+type agentRequest struct {
+	AgentRequest
+	cancel context.CancelCauseFunc
+}
+
+type CancelableAgent struct {
+	conn     *dbus.Conn
+	path     dbus.ObjectPath
+	handler  AgentRequestHandler
+	mu       sync.Mutex
+	requests map[dbus.ObjectPath]*agentRequest
+	logger   *log.Entry
+}
+
+func NewCancelableAgent(conn *dbus.Conn, path dbus.ObjectPath, handler AgentRequestHandler) *CancelableAgent {
+	log.SetReportCaller(true)
+	return &CancelableAgent{
+		conn:     conn,
+		path:     path,
+		handler:  handler,
+		requests: make(map[dbus.ObjectPath]*agentRequest),
+		logger: log.WithFields(log.Fields{
+			"type": "CancelableAgent",
+			"path": path,
+		}),
+	}
+}
+
+func (a *CancelableAgent) GetConnection() *dbus.Conn {
+	return a.conn
+}
+
+func (a *CancelableAgent) GetPath() dbus.ObjectPath {
+	return a.path
+}
+
+func (a *CancelableAgent) GetOutstandingRequests() []AgentRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	requests := make([]AgentRequest, 0, len(a.requests))
+	for _, r := range a.requests {
+		requests = append(requests, r.AgentRequest)
+	}
+	return requests
+}
+
+func (a *CancelableAgent) GetOutstandingRequest(network dbus.ObjectPath) (*AgentRequest, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r, ok := a.requests[network]
+	if !ok {
+		return nil, false
+	}
+	request := r.AgentRequest
+	return &request, true
+}
+
+func (a *CancelableAgent) Release() *dbus.Error {
+	a.logger.Infof("Released CancelableAgent")
+	a.cancelAll("agent released")
+	return nil
+}
+
+func (a *CancelableAgent) RequestPassphrase(network dbus.ObjectPath) (string, *dbus.Error) {
+	ctx, done := a.begin(network, "RequestPassphrase")
+	defer done()
+	passphrase, err := a.handler.RequestPassphrase(ctx, network)
+	return passphrase, a.toDBusError(ctx, network, err)
+}
+
+func (a *CancelableAgent) RequestPrivateKeyPassphrase(network dbus.ObjectPath) (string, *dbus.Error) {
+	ctx, done := a.begin(network, "RequestPrivateKeyPassphrase")
+	defer done()
+	passphrase, err := a.handler.RequestPrivateKeyPassphrase(ctx, network)
+	return passphrase, a.toDBusError(ctx, network, err)
+}
+
+func (a *CancelableAgent) RequestUserNameAndPassword(network dbus.ObjectPath) (string, string, *dbus.Error) {
+	ctx, done := a.begin(network, "RequestUserNameAndPassword")
+	defer done()
+	user, password, err := a.handler.RequestUserNameAndPassword(ctx, network)
+	return user, password, a.toDBusError(ctx, network, err)
+}
+
+func (a *CancelableAgent) RequestUserPassword(network dbus.ObjectPath, user string) (string, *dbus.Error) {
+	ctx, done := a.begin(network, "RequestUserPassword")
+	defer done()
+	password, err := a.handler.RequestUserPassword(ctx, network, user)
+	return password, a.toDBusError(ctx, network, err)
+}
+
+func (a *CancelableAgent) Cancel(reason string) *dbus.Error {
+	a.logger.Infof("Canceling outstanding requests because: %s", reason)
+	a.cancelAll(reason)
+	return nil
+}
+
+func (a *CancelableAgent) begin(network dbus.ObjectPath, method string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	r := &agentRequest{
+		AgentRequest: AgentRequest{
+			Network: network,
+			Method:  method,
+			Started: time.Now(),
+		},
+		cancel: cancel,
+	}
+	a.mu.Lock()
+	if previous, ok := a.requests[network]; ok {
+		a.logger.Warnf("%s for Network %s supersedes outstanding %s", method, network, previous.Method)
+		previous.cancel(&AgentCancelError{Reason: "superseded by " + method})
+	}
+	a.requests[network] = r
+	a.mu.Unlock()
+	a.logger.Debugf("Started %s for Network %s", method, network)
+	return ctx, func() {
+		a.mu.Lock()
+		if a.requests[network] == r {
+			delete(a.requests, network)
+		}
+		a.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+func (a *CancelableAgent) cancelAll(reason string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for network, r := range a.requests {
+		r.cancel(&AgentCancelError{Reason: reason})
+		delete(a.requests, network)
+	}
+}
+
+func (a *CancelableAgent) toDBusError(ctx context.Context, network dbus.ObjectPath, err error) *dbus.Error {
+	if err == nil {
+		return nil
+	}
+	var dbusErr *dbus.Error
+	if errors.As(err, &dbusErr) {
+		return dbusErr
+	}
+	if ctx.Err() != nil {
+		err = context.Cause(ctx)
+	}
+	a.logger.WithFields(log.Fields{
+		"err": err,
+	}).Warnf("Request for Network %s failed", network)
+	return NewAgentCanceledError(err.Error())
+}
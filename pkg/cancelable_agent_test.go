@@ -0,0 +1,109 @@
+package spider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+type blockingAgentRequestHandler struct {
+	started chan dbus.ObjectPath
+	causes  chan error
+}
+
+func newBlockingAgentRequestHandler() *blockingAgentRequestHandler {
+	return &blockingAgentRequestHandler{
+		started: make(chan dbus.ObjectPath, 4),
+		causes:  make(chan error, 4),
+	}
+}
+
+func (h *blockingAgentRequestHandler) wait(ctx context.Context, network dbus.ObjectPath) error {
+	h.started <- network
+	<-ctx.Done()
+	h.causes <- context.Cause(ctx)
+	return ctx.Err()
+}
+
+func (h *blockingAgentRequestHandler) RequestPassphrase(ctx context.Context, network dbus.ObjectPath) (string, error) {
+	return "", h.wait(ctx, network)
+}
+
+func (h *blockingAgentRequestHandler) RequestPrivateKeyPassphrase(ctx context.Context, network dbus.ObjectPath) (string, error) {
+	return "", h.wait(ctx, network)
+}
+
+func (h *blockingAgentRequestHandler) RequestUserNameAndPassword(ctx context.Context, network dbus.ObjectPath) (string, string, error) {
+	return "", "", h.wait(ctx, network)
+}
+
+func (h *blockingAgentRequestHandler) RequestUserPassword(ctx context.Context, network dbus.ObjectPath, user string) (string, error) {
+	return "", h.wait(ctx, network)
+}
+
+func TestCancelableAgentCancel(t *testing.T) {
+	client, server := newTestConnPair(t)
+	exportTestAgentManager(t, server, nil)
+	handler := newBlockingAgentRequestHandler()
+	agent := NewCancelableAgent(client, "/spider/cancelable", handler)
+	s, _ := serveTestAgent(t, client, agent)
+
+	pending := sendTestCallAs(server, testIwdOwner, s.GetPath(), AgentInterface+".RequestPassphrase", testNetworkPath)
+	if network := <-handler.started; network != testNetworkPath {
+		t.Fatalf("handler started for %s; want %s", network, testNetworkPath)
+	}
+	if request, ok := agent.GetOutstandingRequest(testNetworkPath); !ok || request.Method != "RequestPassphrase" {
+		t.Fatalf("outstanding request = %v, %t; want RequestPassphrase", request, ok)
+	}
+	if call := callTestAs(t, server, testIwdOwner, s.GetPath(), AgentInterface+".Cancel", "user-canceled"); call.Err != nil {
+		t.Fatalf("Cancel failed: %s", call.Err)
+	}
+	var cancelErr *AgentCancelError
+	if cause := <-handler.causes; !errors.As(cause, &cancelErr) || cancelErr.Reason != "user-canceled" {
+		t.Errorf("handler context cause = %v; want the Cancel reason", cause)
+	}
+	call := waitForTestCall(t, pending)
+	assertTestDBusError(t, "canceled RequestPassphrase", call.Err, AgentErrorCanceled)
+	if dbusErr, ok := call.Err.(dbus.Error); ok && (len(dbusErr.Body) == 0 || !strings.Contains(dbusErr.Body[0].(string), "user-canceled")) {
+		t.Errorf("canceled RequestPassphrase error body = %v; want the Cancel reason", dbusErr.Body)
+	}
+	if requests := agent.GetOutstandingRequests(); len(requests) != 0 {
+		t.Errorf("outstanding requests after Cancel = %v", requests)
+	}
+}
+
+func TestCancelableAgentSupersede(t *testing.T) {
+	handler := newBlockingAgentRequestHandler()
+	agent := NewCancelableAgent(nil, "/spider/cancelable", handler)
+	first := make(chan *dbus.Error, 1)
+	go func() {
+		_, err := agent.RequestPassphrase(testNetworkPath)
+		first <- err
+	}()
+	<-handler.started
+	second := make(chan *dbus.Error, 1)
+	go func() {
+		_, err := agent.RequestPrivateKeyPassphrase(testNetworkPath)
+		second <- err
+	}()
+	<-handler.started
+	var cancelErr *AgentCancelError
+	if cause := <-handler.causes; !errors.As(cause, &cancelErr) || cancelErr.Reason != "superseded by RequestPrivateKeyPassphrase" {
+		t.Errorf("superseded request cause = %v", cause)
+	}
+	if err := <-first; err == nil || err.Name != AgentErrorCanceled {
+		t.Errorf("superseded request error = %v; want %s", err, AgentErrorCanceled)
+	}
+	if request, ok := agent.GetOutstandingRequest(testNetworkPath); !ok || request.Method != "RequestPrivateKeyPassphrase" {
+		t.Errorf("outstanding request = %v, %t; want RequestPrivateKeyPassphrase", request, ok)
+	}
+	if err := agent.Release(); err != nil {
+		t.Fatalf("Release failed: %s", err)
+	}
+	if err := <-second; err == nil || err.Name != AgentErrorCanceled {
+		t.Errorf("request after Release error = %v; want %s", err, AgentErrorCanceled)
+	}
+}
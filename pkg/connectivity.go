@@ -0,0 +1,160 @@
+package spider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultConnectivityProbeURL       = "http://connectivitycheck.gstatic.com/generate_204"
+	DefaultConnectivityExpectedStatus = http.StatusNoContent
+	DefaultConnectivityTimeout        = 10 * time.Second
+	connectivityMaxBodySize           = 64 * 1024
+)
+
+var (
+	connectivityLogger = log.WithFields(log.Fields{
+		"type": "ConnectivityProber",
+	})
+)
+
+type ConnectivityStatus uint8
+
+const (
+	ConnectivityUnknown ConnectivityStatus = iota
+	ConnectivityOnline
+	ConnectivityCaptive
+	ConnectivityNoInternet
+)
+
+func (cs ConnectivityStatus) String() string {
+	switch cs {
+	case ConnectivityOnline:
+		return "online"
+	case ConnectivityCaptive:
+		return "captive"
+	case ConnectivityNoInternet:
+		return "no-internet"
+	default:
+		return "unknown"
+	}
+}
+
+type ConnectivityResult struct {
+	Status      ConnectivityStatus
+	RedirectURL string
+	StatusCode  int
+	Err         error
+}
+
+func (cr *ConnectivityResult) String() string {
+	return fmt.Sprintf("{Status: %s, RedirectURL: %s, StatusCode: %d, Err: %v}", cr.Status, cr.RedirectURL, cr.StatusCode, cr.Err)
+}
+
+type ConnectivityProber struct {
+	ProbeURL       string
+	ExpectedStatus int
+	ExpectedBody   *string
+	Timeout        time.Duration
+	Client         *http.Client
+}
+
+func NewConnectivityProber() *ConnectivityProber {
+	return &ConnectivityProber{
+		ProbeURL:       DefaultConnectivityProbeURL,
+		ExpectedStatus: DefaultConnectivityExpectedStatus,
+		Timeout:        DefaultConnectivityTimeout,
+	}
+}
+
+func (p *ConnectivityProber) client() *http.Client {
+	client := &http.Client{}
+	if p.Client != nil {
+		*client = *p.Client
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return client
+}
+
+func (p *ConnectivityProber) Probe(ctx context.Context) *ConnectivityResult {
+	probeURL := p.ProbeURL
+	if probeURL == "" {
+		probeURL = DefaultConnectivityProbeURL
+	}
+	expectedStatus := p.ExpectedStatus
+	if expectedStatus == 0 {
+		expectedStatus = DefaultConnectivityExpectedStatus
+	}
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DefaultConnectivityTimeout
+	}
+	logger := connectivityLogger.WithFields(log.Fields{
+		"url": probeURL,
+	})
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("failed to create connectivity probe request")
+		return &ConnectivityResult{Status: ConnectivityUnknown, Err: err}
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+	resp, err := p.client().Do(req)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"err": err,
+		}).Info("Connectivity probe failed")
+		return &ConnectivityResult{Status: ConnectivityNoInternet, Err: err}
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	result := &ConnectivityResult{StatusCode: resp.StatusCode}
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		result.Status = ConnectivityCaptive
+		if location, err2 := resp.Location(); err2 == nil {
+			result.RedirectURL = location.String()
+		}
+		logger.Infof("Connectivity probe redirected to %s", result.RedirectURL)
+		return result
+	}
+	if resp.StatusCode == http.StatusOK && expectedStatus != http.StatusOK {
+		result.Status = ConnectivityCaptive
+		logger.Infof("Connectivity probe returned status %d; expected %d", resp.StatusCode, expectedStatus)
+		return result
+	}
+	if resp.StatusCode != expectedStatus {
+		result.Status = ConnectivityNoInternet
+		logger.Infof("Connectivity probe returned status %d; expected %d", resp.StatusCode, expectedStatus)
+		return result
+	}
+	if p.ExpectedBody != nil {
+		body, err2 := io.ReadAll(io.LimitReader(resp.Body, connectivityMaxBodySize))
+		if err2 != nil {
+			logger.WithFields(log.Fields{
+				"err": err2,
+			}).Info("Failed to read connectivity probe response")
+			result.Status = ConnectivityNoInternet
+			result.Err = err2
+			return result
+		}
+		if string(body) != *p.ExpectedBody {
+			result.Status = ConnectivityCaptive
+			logger.Info("Connectivity probe returned an unexpected body")
+			return result
+		}
+	}
+	result.Status = ConnectivityOnline
+	logger.Debug("Connectivity probe succeeded")
+	return result
+}
@@ -0,0 +1,119 @@
+package spider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConnectivityProberProbe(t *testing.T) {
+	loginPage := "<html><body>Please log in</body></html>"
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		status     ConnectivityStatus
+		statusCode int
+		redirect   string
+		err        bool
+	}{
+		{
+			name: "204",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			status:     ConnectivityOnline,
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name: "302 with Location",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://portal.example.com/login", http.StatusFound)
+			},
+			status:     ConnectivityCaptive,
+			statusCode: http.StatusFound,
+			redirect:   "http://portal.example.com/login",
+		},
+		{
+			name: "200 with login page",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(loginPage))
+			},
+			status:     ConnectivityCaptive,
+			statusCode: http.StatusOK,
+		},
+		{
+			name: "500",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			status:     ConnectivityNoInternet,
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "404",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			status:     ConnectivityNoInternet,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			},
+			status: ConnectivityNoInternet,
+			err:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			prober := NewConnectivityProber()
+			prober.ProbeURL = server.URL
+			prober.Timeout = 200 * time.Millisecond
+			result := prober.Probe(context.Background())
+			if result.Status != tt.status {
+				t.Errorf("Status = %s; want %s", result.Status, tt.status)
+			}
+			if result.StatusCode != tt.statusCode {
+				t.Errorf("StatusCode = %d; want %d", result.StatusCode, tt.statusCode)
+			}
+			if result.RedirectURL != tt.redirect {
+				t.Errorf("RedirectURL = %q; want %q", result.RedirectURL, tt.redirect)
+			}
+			if (result.Err != nil) != tt.err {
+				t.Errorf("Err = %v; want error %t", result.Err, tt.err)
+			}
+		})
+	}
+}
+
+func TestConnectivityProberExpectedBody(t *testing.T) {
+	expected := "success"
+	for _, tt := range []struct {
+		body   string
+		status ConnectivityStatus
+	}{
+		{body: expected, status: ConnectivityOnline},
+		{body: "<html>login</html>", status: ConnectivityCaptive},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(tt.body))
+		}))
+		prober := NewConnectivityProber()
+		prober.ProbeURL = server.URL
+		prober.ExpectedStatus = http.StatusOK
+		prober.ExpectedBody = &expected
+		if result := prober.Probe(context.Background()); result.Status != tt.status {
+			t.Errorf("body %q: Status = %s; want %s", tt.body, result.Status, tt.status)
+		}
+		server.Close()
+	}
+}
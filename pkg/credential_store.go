@@ -0,0 +1,166 @@
+package spider
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultEnvCredentialPrefix = "SPIDER_"
+)
+
+var (
+	ErrCredentialsNotFound = errors.New("credentials not found")
+)
+
+type Credentials struct {
+	Passphrase           string `json:"passphrase,omitempty"`
+	User                 string `json:"user,omitempty"`
+	Password             string `json:"password,omitempty"`
+	PrivateKeyPassphrase string `json:"private_key_passphrase,omitempty"`
+}
+
+type CredentialStore interface {
+	Lookup(ssid, netType string) (*Credentials, error)
+}
+
+type CredentialEntry struct {
+	SSID string `json:"ssid"`
+	Type string `json:"type,omitempty"`
+	Credentials
+}
+
+type MemoryCredentialStore struct {
+	mu      sync.RWMutex
+	entries map[string]Credentials
+}
+
+func NewMemoryCredentialStore() *MemoryCredentialStore {
+	return &MemoryCredentialStore{
+		entries: make(map[string]Credentials),
+	}
+}
+
+func (s *MemoryCredentialStore) Set(ssid, netType string, credentials Credentials) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[credentialKey(ssid, netType)] = credentials
+}
+
+func (s *MemoryCredentialStore) Delete(ssid, netType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, credentialKey(ssid, netType))
+}
+
+func (s *MemoryCredentialStore) Lookup(ssid, netType string) (*Credentials, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range []string{credentialKey(ssid, netType), credentialKey(ssid, "")} {
+		if credentials, ok := s.entries[key]; ok {
+			return &credentials, nil
+		}
+	}
+	return nil, ErrCredentialsNotFound
+}
+
+type JSONFileCredentialStore struct {
+	path   string
+	logger *log.Entry
+}
+
+type credentialFile struct {
+	Networks []CredentialEntry `json:"networks"`
+}
+
+func NewJSONFileCredentialStore(path string) *JSONFileCredentialStore {
+	return &JSONFileCredentialStore{
+		path: path,
+		logger: log.WithFields(log.Fields{
+			"type": "JSONFileCredentialStore",
+			"path": path,
+		}),
+	}
+}
+
+func (s *JSONFileCredentialStore) Lookup(ssid, netType string) (*Credentials, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		s.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to read credential file")
+		return nil, fmt.Errorf("failed to read credential file %s: %s", s.path, err)
+	}
+	var file credentialFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		s.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to parse credential file")
+		return nil, fmt.Errorf("failed to parse credential file %s: %s", s.path, err)
+	}
+	return lookupCredentialEntries(file.Networks, ssid, netType)
+}
+
+type EnvCredentialStore struct {
+	prefix string
+}
+
+func NewEnvCredentialStore(prefix string) *EnvCredentialStore {
+	if prefix == "" {
+		prefix = DefaultEnvCredentialPrefix
+	}
+	return &EnvCredentialStore{
+		prefix: prefix,
+	}
+}
+
+func (s *EnvCredentialStore) Lookup(ssid, netType string) (*Credentials, error) {
+	name := s.prefix + envCredentialName(ssid) + "_"
+	credentials := &Credentials{}
+	switch netType {
+	case NetworkTypePSK:
+		credentials.Passphrase = os.Getenv(name + "PASSPHRASE")
+	case NetworkType8021X:
+		credentials.User = os.Getenv(name + "USER")
+		credentials.Password = os.Getenv(name + "PASSWORD")
+		credentials.PrivateKeyPassphrase = os.Getenv(name + "PRIVATE_KEY_PASSPHRASE")
+	}
+	if *credentials == (Credentials{}) {
+		return nil, ErrCredentialsNotFound
+	}
+	return credentials, nil
+}
+
+func lookupCredentialEntries(entries []CredentialEntry, ssid, netType string) (*Credentials, error) {
+	var fallback *Credentials
+	for i := range entries {
+		if entries[i].SSID != ssid {
+			continue
+		}
+		if entries[i].Type == netType {
+			return &entries[i].Credentials, nil
+		}
+		if entries[i].Type == "" && fallback == nil {
+			fallback = &entries[i].Credentials
+		}
+	}
+	if fallback == nil {
+		return nil, ErrCredentialsNotFound
+	}
+	return fallback, nil
+}
+
+func credentialKey(ssid, netType string) string {
+	return netType + "/" + ssid
+}
+
+func envCredentialName(ssid string) string {
+	return strings.ToUpper(hex.EncodeToString([]byte(ssid)))
+}
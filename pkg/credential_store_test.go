@@ -0,0 +1,60 @@
+package spider
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEnvCredentialStore(t *testing.T) {
+	t.Setenv("TEST_"+envCredentialName("my-net")+"_PASSPHRASE", "dash passphrase")
+	t.Setenv("TEST_"+envCredentialName("my_net")+"_PASSPHRASE", "underscore passphrase")
+	t.Setenv("TEST_"+envCredentialName("My-Net")+"_PASSPHRASE", "mixed case passphrase")
+	t.Setenv("TEST_"+envCredentialName("Corp")+"_PASSPHRASE", "corp passphrase")
+	t.Setenv("TEST_"+envCredentialName("Corp")+"_USER", "user")
+	t.Setenv("TEST_"+envCredentialName("Corp")+"_PASSWORD", "secret")
+	t.Setenv("TEST_"+envCredentialName("Corp")+"_PRIVATE_KEY_PASSPHRASE", "key passphrase")
+	store := NewEnvCredentialStore("TEST_")
+	tests := []struct {
+		ssid        string
+		netType     string
+		credentials *Credentials
+	}{
+		{ssid: "my-net", netType: NetworkTypePSK, credentials: &Credentials{Passphrase: "dash passphrase"}},
+		{ssid: "my_net", netType: NetworkTypePSK, credentials: &Credentials{Passphrase: "underscore passphrase"}},
+		{ssid: "My-Net", netType: NetworkTypePSK, credentials: &Credentials{Passphrase: "mixed case passphrase"}},
+		{ssid: "my net", netType: NetworkTypePSK},
+		{ssid: "MY_NET", netType: NetworkTypePSK},
+		{ssid: "Corp", netType: NetworkTypePSK, credentials: &Credentials{Passphrase: "corp passphrase"}},
+		{ssid: "Corp", netType: NetworkType8021X, credentials: &Credentials{User: "user", Password: "secret", PrivateKeyPassphrase: "key passphrase"}},
+		{ssid: "my-net", netType: NetworkType8021X},
+		{ssid: "my-net", netType: NetworkTypeOpen},
+	}
+	for _, tt := range tests {
+		credentials, err := store.Lookup(tt.ssid, tt.netType)
+		if tt.credentials == nil {
+			if !errors.Is(err, ErrCredentialsNotFound) {
+				t.Errorf("Lookup(%q, %s) = %+v, %v; want %v", tt.ssid, tt.netType, credentials, err, ErrCredentialsNotFound)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Lookup(%q, %s) failed: %s", tt.ssid, tt.netType, err)
+		} else if *credentials != *tt.credentials {
+			t.Errorf("Lookup(%q, %s) = %+v; want %+v", tt.ssid, tt.netType, *credentials, *tt.credentials)
+		}
+	}
+}
+
+func TestEnvCredentialName(t *testing.T) {
+	tests := map[string]string{
+		"Home":     "486F6D65",
+		"my-net":   "6D792D6E6574",
+		"my_net":   "6D795F6E6574",
+		"\xff\x00": "FF00",
+	}
+	for ssid, expected := range tests {
+		if name := envCredentialName(ssid); name != expected {
+			t.Errorf("envCredentialName(%q) = %s; want %s", ssid, name, expected)
+		}
+	}
+}
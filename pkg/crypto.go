@@ -0,0 +1,103 @@
+package spider
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	keyDerivationScrypt = "scrypt"
+	scryptN             = 1 << 15
+	scryptR             = 8
+	scryptP             = 1
+	scryptSaltLength    = 16
+	aesKeyLength        = 32
+)
+
+type keyDerivation struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	N         int    `json:"n"`
+	R         int    `json:"r"`
+	P         int    `json:"p"`
+}
+
+func newKeyDerivation() (*keyDerivation, error) {
+	salt := make([]byte, scryptSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %s", err)
+	}
+	return &keyDerivation{
+		Algorithm: keyDerivationScrypt,
+		Salt:      salt,
+		N:         scryptN,
+		R:         scryptR,
+		P:         scryptP,
+	}, nil
+}
+
+func (kd *keyDerivation) deriveKey(passphrase string) ([]byte, error) {
+	if kd.Algorithm != keyDerivationScrypt {
+		return nil, fmt.Errorf("unsupported key derivation algorithm %s", kd.Algorithm)
+	}
+	if kd.N != scryptN || kd.R != scryptR || kd.P != scryptP {
+		return nil, fmt.Errorf("unsupported scrypt parameters N=%d, r=%d, p=%d", kd.N, kd.R, kd.P)
+	}
+	if len(kd.Salt) != scryptSaltLength {
+		return nil, fmt.Errorf("invalid salt length %d; expected %d", len(kd.Salt), scryptSaltLength)
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+	key, err := scrypt.Key([]byte(passphrase), kd.Salt, kd.N, kd.R, kd.P, aesKeyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %s", err)
+	}
+	return key, nil
+}
+
+func sealAESGCM(key, plaintext, additionalData []byte) ([]byte, []byte, error) {
+	gcm, err := newAESGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce: %s", err)
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+func openAESGCM(key, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	gcm, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: wrong key or corrupted data")
+	}
+	return plaintext, nil
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != aesKeyLength {
+		return nil, fmt.Errorf("invalid key length %d; expected %d", len(key), aesKeyLength)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %s", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %s", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,98 @@
+package spider
+
+import (
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+type AddressFamily string
+
+const (
+	AddressFamilyIPv4 AddressFamily = "IPv4"
+	AddressFamilyIPv6 AddressFamily = "IPv6"
+)
+
+type DNSApplier interface {
+	ApplyDNS(device dbus.ObjectPath, family AddressFamily, config *NetworkConfigurationConfig) error
+	RevertDNS(device dbus.ObjectPath, family AddressFamily) error
+}
+
+type dnsSettings struct {
+	servers []string
+	domains []string
+	mdns    *string
+}
+
+func newDNSSettings(config *NetworkConfigurationConfig) *dnsSettings {
+	s := &dnsSettings{}
+	if config.DomainNameServers != nil {
+		s.servers = append(s.servers, *config.DomainNameServers...)
+	}
+	if config.DomainNames != nil {
+		s.domains = append(s.domains, *config.DomainNames...)
+	}
+	if config.MDNS != nil {
+		mdns := *config.MDNS
+		s.mdns = &mdns
+	}
+	return s
+}
+
+func orderedDNSSettings(families map[AddressFamily]*dnsSettings) []*dnsSettings {
+	ordered := make([]*dnsSettings, 0, len(families))
+	for _, family := range []AddressFamily{AddressFamilyIPv4, AddressFamilyIPv6} {
+		if s, ok := families[family]; ok {
+			ordered = append(ordered, s)
+		}
+	}
+	return ordered
+}
+
+func mergeDNSSettings(settings ...*dnsSettings) *dnsSettings {
+	merged := &dnsSettings{}
+	seenServers := make(map[string]bool)
+	seenDomains := make(map[string]bool)
+	for _, s := range settings {
+		for _, server := range s.servers {
+			if !seenServers[server] {
+				seenServers[server] = true
+				merged.servers = append(merged.servers, server)
+			}
+		}
+		for _, domain := range s.domains {
+			if !seenDomains[domain] {
+				seenDomains[domain] = true
+				merged.domains = append(merged.domains, domain)
+			}
+		}
+		if merged.mdns == nil {
+			merged.mdns = s.mdns
+		}
+	}
+	return merged
+}
+
+func NewDNSNetworkConfigurationCallbacks(applier DNSApplier) NetworkConfigurationCallbacks {
+	logger := log.WithFields(log.Fields{
+		"type": "DNSNetworkConfigurationCallbacks",
+	})
+	revert := func(family AddressFamily) func(dbus.ObjectPath, string) {
+		return func(device dbus.ObjectPath, reason string) {
+			if err := applier.RevertDNS(device, family); err != nil {
+				logger.WithFields(log.Fields{
+					"err": err,
+				}).Errorf("Failed to revert %s DNS configuration of Device %s", family, device)
+			}
+		}
+	}
+	return NetworkConfigurationCallbacks{
+		ConfigureIPv4: func(device dbus.ObjectPath, config *NetworkConfigurationConfig) error {
+			return applier.ApplyDNS(device, AddressFamilyIPv4, config)
+		},
+		ConfigureIPv6: func(device dbus.ObjectPath, config *NetworkConfigurationConfig) error {
+			return applier.ApplyDNS(device, AddressFamilyIPv6, config)
+		},
+		CancelIPv4: revert(AddressFamilyIPv4),
+		CancelIPv6: revert(AddressFamilyIPv6),
+	}
+}
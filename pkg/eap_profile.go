@@ -0,0 +1,255 @@
+package spider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	EAPTTLSPhase2PAP      = "Tunneled-PAP"
+	EAPTTLSPhase2MSCHAPv2 = "Tunneled-MSCHAPv2"
+)
+
+type EAPProfileBuilder interface {
+	Build() (*NetworkProfile, error)
+}
+
+type EAPPEAPProfile struct {
+	SSID              string
+	Identity          string
+	AnonymousIdentity string
+	Password          string
+	CACert            string
+	ServerDomainMask  string
+}
+
+type EAPTTLSProfile struct {
+	SSID              string
+	Identity          string
+	AnonymousIdentity string
+	Password          string
+	Phase2Method      string
+	CACert            string
+	ServerDomainMask  string
+}
+
+type EAPTLSProfile struct {
+	SSID                string
+	Identity            string
+	CACert              string
+	ClientCert          string
+	ClientKey           string
+	ClientKeyPassphrase string
+	ServerDomainMask    string
+}
+
+type EAPPWDProfile struct {
+	SSID     string
+	Identity string
+	Password string
+}
+
+func (b *EAPPEAPProfile) Build() (*NetworkProfile, error) {
+	if b.Identity == "" || b.Password == "" {
+		return nil, fmt.Errorf("%w: EAP-PEAP requires an Identity and Password", ErrInvalidNetworkProfile)
+	}
+	eap := map[string]string{
+		"EAP-Method":               "PEAP",
+		"EAP-PEAP-Phase2-Method":   "MSCHAPV2",
+		"EAP-PEAP-Phase2-Identity": b.Identity,
+		"EAP-PEAP-Phase2-Password": b.Password,
+	}
+	setEAPIdentity(eap, b.AnonymousIdentity, b.Identity)
+	if _, err := setEAPServerValidation(eap, "EAP-PEAP-", b.CACert, b.ServerDomainMask); err != nil {
+		return nil, err
+	}
+	return newEAPNetworkProfile(b.SSID, eap)
+}
+
+func (b *EAPTTLSProfile) Build() (*NetworkProfile, error) {
+	if b.Identity == "" || b.Password == "" {
+		return nil, fmt.Errorf("%w: EAP-TTLS requires an Identity and Password", ErrInvalidNetworkProfile)
+	}
+	phase2Method := b.Phase2Method
+	if phase2Method == "" {
+		phase2Method = EAPTTLSPhase2MSCHAPv2
+	}
+	if phase2Method != EAPTTLSPhase2PAP && phase2Method != EAPTTLSPhase2MSCHAPv2 {
+		return nil, fmt.Errorf("%w: unsupported EAP-TTLS phase 2 method %s; valid methods are %s and %s", ErrInvalidNetworkProfile, phase2Method, EAPTTLSPhase2PAP, EAPTTLSPhase2MSCHAPv2)
+	}
+	eap := map[string]string{
+		"EAP-Method":               "TTLS",
+		"EAP-TTLS-Phase2-Method":   phase2Method,
+		"EAP-TTLS-Phase2-Identity": b.Identity,
+		"EAP-TTLS-Phase2-Password": b.Password,
+	}
+	setEAPIdentity(eap, b.AnonymousIdentity, b.Identity)
+	if _, err := setEAPServerValidation(eap, "EAP-TTLS-", b.CACert, b.ServerDomainMask); err != nil {
+		return nil, err
+	}
+	return newEAPNetworkProfile(b.SSID, eap)
+}
+
+func (b *EAPTLSProfile) Build() (*NetworkProfile, error) {
+	if b.Identity == "" || b.ClientCert == "" || b.ClientKey == "" {
+		return nil, fmt.Errorf("%w: EAP-TLS requires an Identity, ClientCert and ClientKey", ErrInvalidNetworkProfile)
+	}
+	eap := map[string]string{
+		"EAP-Method":         "TLS",
+		"EAP-Identity":       b.Identity,
+		"EAP-TLS-ClientCert": b.ClientCert,
+		"EAP-TLS-ClientKey":  b.ClientKey,
+	}
+	if b.ClientKeyPassphrase != "" {
+		eap["EAP-TLS-ClientKeyPassphrase"] = b.ClientKeyPassphrase
+	}
+	caCerts, err := setEAPServerValidation(eap, "EAP-TLS-", b.CACert, b.ServerDomainMask)
+	if err != nil {
+		return nil, err
+	}
+	var roots *x509.CertPool
+	if len(caCerts) > 0 {
+		roots = x509.NewCertPool()
+		for _, cert := range caCerts {
+			roots.AddCert(cert)
+		}
+	}
+	if err = validateEAPClientCertificate(b.ClientCert, b.ClientKey, b.ClientKeyPassphrase, roots); err != nil {
+		return nil, err
+	}
+	return newEAPNetworkProfile(b.SSID, eap)
+}
+
+func (b *EAPPWDProfile) Build() (*NetworkProfile, error) {
+	if b.Identity == "" || b.Password == "" {
+		return nil, fmt.Errorf("%w: EAP-PWD requires an Identity and Password", ErrInvalidNetworkProfile)
+	}
+	eap := map[string]string{
+		"EAP-Method":       "PWD",
+		"EAP-Identity":     b.Identity,
+		"EAP-PWD-Password": b.Password,
+	}
+	return newEAPNetworkProfile(b.SSID, eap)
+}
+
+func newEAPNetworkProfile(ssid string, eap map[string]string) (*NetworkProfile, error) {
+	p := NewNetworkProfile(ssid, NetworkType8021X)
+	p.Security.EAP = eap
+	if err := p.Validate(); err != nil {
+		log.Errorf("failed to build %s profile for %s: %s", eap["EAP-Method"], ssid, err)
+		return nil, err
+	}
+	log.Debugf("Built EAP-%s profile for %s", eap["EAP-Method"], ssid)
+	return p, nil
+}
+
+func setEAPIdentity(eap map[string]string, anonymousIdentity, identity string) {
+	if anonymousIdentity != "" {
+		eap["EAP-Identity"] = anonymousIdentity
+	} else {
+		eap["EAP-Identity"] = identity
+	}
+}
+
+func setEAPServerValidation(eap map[string]string, prefix, caCert, serverDomainMask string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	if caCert != "" {
+		var err error
+		if certs, err = loadPEMCertificates(caCert); err != nil {
+			return nil, err
+		}
+		for _, cert := range certs {
+			if !cert.IsCA {
+				return nil, fmt.Errorf("%w: certificate %s in %s is not a CA certificate", ErrInvalidNetworkProfile, cert.Subject, caCert)
+			}
+		}
+		eap[prefix+"CACert"] = caCert
+	} else {
+		log.Warnf("No CA certificate configured for EAP-%s; the server certificate will not be validated", eap["EAP-Method"])
+	}
+	if serverDomainMask != "" {
+		eap[prefix+"ServerDomainMask"] = serverDomainMask
+	}
+	return certs, nil
+}
+
+func loadPEMCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read certificate %s: %s", ErrInvalidNetworkProfile, path, err)
+	}
+	now := time.Now()
+	certs := make([]*x509.Certificate, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err2 := x509.ParseCertificate(block.Bytes)
+		if err2 != nil {
+			return nil, fmt.Errorf("%w: failed to parse certificate in %s: %s", ErrInvalidNetworkProfile, path, err2)
+		}
+		if now.Before(cert.NotBefore) {
+			return nil, fmt.Errorf("%w: certificate %s in %s is not valid before %s", ErrInvalidNetworkProfile, cert.Subject, path, cert.NotBefore)
+		}
+		if now.After(cert.NotAfter) {
+			return nil, fmt.Errorf("%w: certificate %s in %s expired on %s", ErrInvalidNetworkProfile, cert.Subject, path, cert.NotAfter)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: no PEM certificates found in %s", ErrInvalidNetworkProfile, path)
+	}
+	return certs, nil
+}
+
+func validateEAPClientCertificate(certPath, keyPath, passphrase string, roots *x509.CertPool) error {
+	certs, err := loadPEMCertificates(certPath)
+	if err != nil {
+		return err
+	}
+	leaf := certs[0]
+	if roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		if _, err = leaf.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}); err != nil {
+			return fmt.Errorf("%w: client certificate %s does not chain to the CA certificate: %s", ErrInvalidNetworkProfile, certPath, err)
+		}
+	}
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read client key %s: %s", ErrInvalidNetworkProfile, keyPath, err)
+	}
+	block, _ := pem.Decode(keyData)
+	if block == nil {
+		return fmt.Errorf("%w: no PEM key found in %s", ErrInvalidNetworkProfile, keyPath)
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] == "4,ENCRYPTED" {
+		if passphrase == "" {
+			return fmt.Errorf("%w: client key %s is encrypted but no ClientKeyPassphrase was given", ErrInvalidNetworkProfile, keyPath)
+		}
+		log.Infof("Client key %s is encrypted; skipping key and certificate match check", keyPath)
+		return nil
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})
+	if _, err = tls.X509KeyPair(certPEM, pem.EncodeToMemory(block)); err != nil {
+		return fmt.Errorf("%w: client key %s does not match certificate %s: %s", ErrInvalidNetworkProfile, keyPath, certPath, err)
+	}
+	return nil
+}
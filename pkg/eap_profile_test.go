@@ -0,0 +1,156 @@
+package spider
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCertificate(t *testing.T, name string, isCA bool, notAfter time.Time, parent *testCertificate) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	issuer, issuerKey := template, key
+	if parent != nil {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{cert: cert, key: key}
+}
+
+func (c *testCertificate) writeCert(t *testing.T, path string) string {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func (c *testCertificate) writeKey(t *testing.T, path string) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEAPTLSProfileBuild(t *testing.T) {
+	dir := t.TempDir()
+	notAfter := time.Now().Add(24 * time.Hour)
+	ca := newTestCertificate(t, "Test CA", true, notAfter, nil)
+	otherCA := newTestCertificate(t, "Other CA", true, notAfter, nil)
+	client := newTestCertificate(t, "client", false, notAfter, ca)
+	other := newTestCertificate(t, "other", false, notAfter, ca)
+	expired := newTestCertificate(t, "expired", false, time.Now().Add(-time.Minute), ca)
+
+	caPath := ca.writeCert(t, filepath.Join(dir, "ca.pem"))
+	otherCAPath := otherCA.writeCert(t, filepath.Join(dir, "other-ca.pem"))
+	notCAPath := client.writeCert(t, filepath.Join(dir, "not-ca.pem"))
+	clientCert := client.writeCert(t, filepath.Join(dir, "client.pem"))
+	clientKey := client.writeKey(t, filepath.Join(dir, "client.key"))
+	otherKey := other.writeKey(t, filepath.Join(dir, "other.key"))
+	expiredCert := expired.writeCert(t, filepath.Join(dir, "expired.pem"))
+	expiredKey := expired.writeKey(t, filepath.Join(dir, "expired.key"))
+	encryptedKey := filepath.Join(dir, "encrypted.key")
+	if err := os.WriteFile(encryptedKey, pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte{0}}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	builder := &EAPTLSProfile{
+		SSID:             "Enterprise",
+		Identity:         "client@example.com",
+		CACert:           caPath,
+		ClientCert:       clientCert,
+		ClientKey:        clientKey,
+		ServerDomainMask: "radius.example.com",
+	}
+	p, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %s", err)
+	}
+	for key, value := range map[string]string{
+		"EAP-Method":               "TLS",
+		"EAP-Identity":             "client@example.com",
+		"EAP-TLS-CACert":           caPath,
+		"EAP-TLS-ClientCert":       clientCert,
+		"EAP-TLS-ClientKey":        clientKey,
+		"EAP-TLS-ServerDomainMask": "radius.example.com",
+	} {
+		if p.Security.EAP[key] != value {
+			t.Errorf("%s = %q; want %q", key, p.Security.EAP[key], value)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(b *EAPTLSProfile)
+	}{
+		{name: "CA file is not a CA", modify: func(b *EAPTLSProfile) { b.CACert = notCAPath }},
+		{name: "missing CA file", modify: func(b *EAPTLSProfile) { b.CACert = filepath.Join(dir, "missing.pem") }},
+		{name: "client certificate from another CA", modify: func(b *EAPTLSProfile) { b.CACert = otherCAPath }},
+		{name: "mismatched key", modify: func(b *EAPTLSProfile) { b.ClientKey = otherKey }},
+		{name: "expired client certificate", modify: func(b *EAPTLSProfile) { b.ClientCert, b.ClientKey = expiredCert, expiredKey }},
+		{name: "encrypted key without passphrase", modify: func(b *EAPTLSProfile) { b.ClientKey = encryptedKey }},
+	}
+	for _, tt := range tests {
+		b := *builder
+		tt.modify(&b)
+		if _, err = b.Build(); !errors.Is(err, ErrInvalidNetworkProfile) {
+			t.Errorf("%s: Build error = %v; want %v", tt.name, err, ErrInvalidNetworkProfile)
+		}
+	}
+
+	b := *builder
+	b.ClientKey = encryptedKey
+	b.ClientKeyPassphrase = "secret"
+	if _, err = b.Build(); err != nil {
+		t.Errorf("encrypted key with passphrase: Build failed: %s", err)
+	}
+}
@@ -0,0 +1,347 @@
+package spider
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	EncryptedCredentialStoreVersion = 1
+	encryptedCredentialStoreAD      = "spider-credential-store"
+	credentialStoreMode             = 0600
+	credentialStoreDirMode          = 0700
+)
+
+type encryptedCredentialEnvelope struct {
+	Version    int            `json:"version"`
+	KDF        *keyDerivation `json:"kdf,omitempty"`
+	Nonce      []byte         `json:"nonce"`
+	Ciphertext []byte         `json:"ciphertext"`
+}
+
+type EncryptedFileCredentialStore struct {
+	mu         sync.Mutex
+	path       string
+	key        []byte
+	passphrase string
+	kdf        *keyDerivation
+	logger     *log.Entry
+}
+
+func NewEncryptedFileCredentialStoreWithKeyFile(path, keyFile string) (*EncryptedFileCredentialStore, error) {
+	key, err := loadCredentialKeyFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	s := &EncryptedFileCredentialStore{
+		path:   path,
+		key:    key,
+		logger: newEncryptedFileCredentialStoreLogger(path),
+	}
+	if _, err = s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func NewEncryptedFileCredentialStoreWithPassphrase(path, passphrase string) (*EncryptedFileCredentialStore, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+	s := &EncryptedFileCredentialStore{
+		path:       path,
+		passphrase: passphrase,
+		logger:     newEncryptedFileCredentialStoreLogger(path),
+	}
+	if _, err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newEncryptedFileCredentialStoreLogger(path string) *log.Entry {
+	return log.WithFields(log.Fields{
+		"type": "EncryptedFileCredentialStore",
+		"path": path,
+	})
+}
+
+func newCredentialKeyFileLogger(path string) *log.Entry {
+	return log.WithFields(log.Fields{
+		"type": "CredentialKeyFile",
+		"path": path,
+	})
+}
+
+func GenerateCredentialKeyFile(path string) error {
+	key := make([]byte, aesKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate key: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), credentialStoreDirMode); err != nil {
+		return fmt.Errorf("failed to create key directory for %s: %s", path, err)
+	}
+	if err := writeFileAtomic(path, []byte(hex.EncodeToString(key)+"\n"), credentialStoreMode); err != nil {
+		newCredentialKeyFileLogger(path).WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to write key file")
+		return err
+	}
+	newCredentialKeyFileLogger(path).Debugf("Generated credential key file")
+	return nil
+}
+
+func (s *EncryptedFileCredentialStore) Lookup(ssid, netType string) (*Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return lookupCredentialEntries(entries, ssid, netType)
+}
+
+func (s *EncryptedFileCredentialStore) Add(entry CredentialEntry) error {
+	if entry.SSID == "" {
+		return fmt.Errorf("credential entry requires an SSID")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range entries {
+		if entries[i].SSID == entry.SSID && entries[i].Type == entry.Type {
+			entries[i] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		entries = append(entries, entry)
+	}
+	if err = s.save(entries); err != nil {
+		return err
+	}
+	s.logger.Infof("Stored credentials for %s (%s)", entry.SSID, entry.Type)
+	return nil
+}
+
+func (s *EncryptedFileCredentialStore) Remove(ssid, netType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	kept := make([]CredentialEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.SSID != ssid || entry.Type != netType {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) == len(entries) {
+		return ErrCredentialsNotFound
+	}
+	if err = s.save(kept); err != nil {
+		return err
+	}
+	s.logger.Infof("Removed credentials for %s (%s)", ssid, netType)
+	return nil
+}
+
+func (s *EncryptedFileCredentialStore) List() ([]CredentialEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	listed := make([]CredentialEntry, 0, len(entries))
+	for _, entry := range entries {
+		listed = append(listed, CredentialEntry{
+			SSID: entry.SSID,
+			Type: entry.Type,
+			Credentials: Credentials{
+				User: entry.User,
+			},
+		})
+	}
+	sort.Slice(listed, func(i, j int) bool {
+		if listed[i].SSID != listed[j].SSID {
+			return listed[i].SSID < listed[j].SSID
+		}
+		return listed[i].Type < listed[j].Type
+	})
+	return listed, nil
+}
+
+func (s *EncryptedFileCredentialStore) RotateKeyFile(keyFile string) error {
+	key, err := loadCredentialKeyFile(keyFile)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	s.key = key
+	s.passphrase = ""
+	s.kdf = nil
+	if err = s.save(entries); err != nil {
+		return err
+	}
+	s.logger.Infof("Rotated credential store to key file %s", keyFile)
+	return nil
+}
+
+func (s *EncryptedFileCredentialStore) RotatePassphrase(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase must not be empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	s.key = nil
+	s.passphrase = passphrase
+	s.kdf = nil
+	if err = s.save(entries); err != nil {
+		return err
+	}
+	s.logger.Infof("Rotated credential store to a new passphrase")
+	return nil
+}
+
+func (s *EncryptedFileCredentialStore) load() ([]CredentialEntry, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []CredentialEntry{}, nil
+	}
+	if err != nil {
+		s.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to read credential store")
+		return nil, fmt.Errorf("failed to read credential store %s: %s", s.path, err)
+	}
+	var envelope encryptedCredentialEnvelope
+	if err = json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse credential store %s: %s", s.path, err)
+	}
+	if envelope.Version != EncryptedCredentialStoreVersion {
+		return nil, fmt.Errorf("unsupported credential store version %d", envelope.Version)
+	}
+	key, err := s.keyFor(envelope.KDF)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := openAESGCM(key, envelope.Nonce, envelope.Ciphertext, []byte(encryptedCredentialStoreAD))
+	if err != nil {
+		s.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to decrypt credential store")
+		return nil, err
+	}
+	var file credentialFile
+	if err = json.Unmarshal(plaintext, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted credential store %s: %s", s.path, err)
+	}
+	if file.Networks == nil {
+		file.Networks = []CredentialEntry{}
+	}
+	return file.Networks, nil
+}
+
+func (s *EncryptedFileCredentialStore) save(entries []CredentialEntry) error {
+	plaintext, err := json.Marshal(&credentialFile{Networks: entries})
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %s", err)
+	}
+	if s.passphrase != "" && s.kdf == nil {
+		if s.kdf, err = newKeyDerivation(); err != nil {
+			return err
+		}
+		if s.key, err = s.kdf.deriveKey(s.passphrase); err != nil {
+			return err
+		}
+	}
+	nonce, ciphertext, err := sealAESGCM(s.key, plaintext, []byte(encryptedCredentialStoreAD))
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(&encryptedCredentialEnvelope{
+		Version:    EncryptedCredentialStoreVersion,
+		KDF:        s.kdf,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal credential store: %s", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(s.path), credentialStoreDirMode); err != nil {
+		return fmt.Errorf("failed to create credential store directory for %s: %s", s.path, err)
+	}
+	if err = writeFileAtomic(s.path, data, credentialStoreMode); err != nil {
+		s.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to write credential store")
+		return err
+	}
+	return nil
+}
+
+func (s *EncryptedFileCredentialStore) keyFor(kdf *keyDerivation) ([]byte, error) {
+	if s.passphrase == "" {
+		if kdf != nil {
+			return nil, fmt.Errorf("credential store %s is protected by a passphrase, not a key file", s.path)
+		}
+		return s.key, nil
+	}
+	if kdf == nil {
+		return nil, fmt.Errorf("credential store %s is protected by a key file, not a passphrase", s.path)
+	}
+	if s.kdf != nil && s.key != nil && bytes.Equal(s.kdf.Salt, kdf.Salt) && s.kdf.N == kdf.N && s.kdf.R == kdf.R && s.kdf.P == kdf.P {
+		return s.key, nil
+	}
+	key, err := kdf.deriveKey(s.passphrase)
+	if err != nil {
+		return nil, err
+	}
+	s.kdf = kdf
+	s.key = key
+	return key, nil
+}
+
+func loadCredentialKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		newCredentialKeyFileLogger(path).WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to read key file")
+		return nil, fmt.Errorf("failed to read key file %s: %s", path, err)
+	}
+	encoded := bytes.TrimSpace(data)
+	if len(encoded) != hex.EncodedLen(aesKeyLength) {
+		return nil, fmt.Errorf("key file %s must contain exactly %d hex digits; got %d bytes", path, hex.EncodedLen(aesKeyLength), len(encoded))
+	}
+	key := make([]byte, aesKeyLength)
+	if _, err = hex.Decode(key, encoded); err != nil {
+		return nil, fmt.Errorf("key file %s must contain exactly %d hex digits: %s", path, hex.EncodedLen(aesKeyLength), err)
+	}
+	return key, nil
+}
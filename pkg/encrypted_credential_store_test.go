@@ -0,0 +1,183 @@
+package spider
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestCredentialKeyFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := GenerateCredentialKeyFile(path); err != nil {
+		t.Fatalf("GenerateCredentialKeyFile failed: %s", err)
+	}
+	return path
+}
+
+func assertTestCredentialLookup(t *testing.T, s *EncryptedFileCredentialStore, ssid, netType, passphrase string) {
+	t.Helper()
+	credentials, err := s.Lookup(ssid, netType)
+	if err != nil {
+		t.Fatalf("Lookup(%q, %s) failed: %s", ssid, netType, err)
+	}
+	if credentials.Passphrase != passphrase {
+		t.Errorf("Lookup(%q, %s) Passphrase = %q; want %q", ssid, netType, credentials.Passphrase, passphrase)
+	}
+}
+
+func TestEncryptedFileCredentialStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store", "credentials.json")
+	keyFile := newTestCredentialKeyFile(t, dir, "key")
+	s, err := NewEncryptedFileCredentialStoreWithKeyFile(path, keyFile)
+	if err != nil {
+		t.Fatalf("NewEncryptedFileCredentialStoreWithKeyFile failed: %s", err)
+	}
+	if err = s.Add(CredentialEntry{SSID: "Home", Type: NetworkTypePSK, Credentials: Credentials{Passphrase: "home passphrase"}}); err != nil {
+		t.Fatalf("Add failed: %s", err)
+	}
+	if err = s.Add(CredentialEntry{SSID: "Corp", Type: NetworkType8021X, Credentials: Credentials{User: "user", Password: "secret"}}); err != nil {
+		t.Fatalf("Add failed: %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"home passphrase", "secret", "Home"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("credential store contains %q in plaintext", secret)
+		}
+	}
+	if info, err2 := os.Stat(path); err2 != nil || info.Mode().Perm() != credentialStoreMode {
+		t.Errorf("credential store mode = %v, %v; want %o", info.Mode().Perm(), err2, credentialStoreMode)
+	}
+
+	reopened, err := NewEncryptedFileCredentialStoreWithKeyFile(path, keyFile)
+	if err != nil {
+		t.Fatalf("reopening the store failed: %s", err)
+	}
+	assertTestCredentialLookup(t, reopened, "Home", NetworkTypePSK, "home passphrase")
+	listed, err := reopened.List()
+	if err != nil {
+		t.Fatalf("List failed: %s", err)
+	}
+	if len(listed) != 2 || listed[0].SSID != "Corp" || listed[0].User != "user" || listed[0].Password != "" || listed[1].Passphrase != "" {
+		t.Errorf("List = %+v; want both entries without secrets", listed)
+	}
+	if err = reopened.Remove("Home", NetworkTypePSK); err != nil {
+		t.Fatalf("Remove failed: %s", err)
+	}
+	if _, err = reopened.Lookup("Home", NetworkTypePSK); err != ErrCredentialsNotFound {
+		t.Errorf("Lookup after Remove error = %v; want %v", err, ErrCredentialsNotFound)
+	}
+}
+
+func TestEncryptedFileCredentialStoreRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "credentials.json")
+	firstKeyFile := newTestCredentialKeyFile(t, dir, "first")
+	secondKeyFile := newTestCredentialKeyFile(t, dir, "second")
+	s, err := NewEncryptedFileCredentialStoreWithKeyFile(path, firstKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Add(CredentialEntry{SSID: "Home", Type: NetworkTypePSK, Credentials: Credentials{Passphrase: "home passphrase"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.RotatePassphrase("store passphrase"); err != nil {
+		t.Fatalf("RotatePassphrase failed: %s", err)
+	}
+	assertTestCredentialLookup(t, s, "Home", NetworkTypePSK, "home passphrase")
+	if _, err = NewEncryptedFileCredentialStoreWithKeyFile(path, firstKeyFile); err == nil {
+		t.Errorf("old key file still opens the store after rotating to a passphrase")
+	}
+	withPassphrase, err := NewEncryptedFileCredentialStoreWithPassphrase(path, "store passphrase")
+	if err != nil {
+		t.Fatalf("opening the store with the new passphrase failed: %s", err)
+	}
+	assertTestCredentialLookup(t, withPassphrase, "Home", NetworkTypePSK, "home passphrase")
+
+	if err = withPassphrase.RotateKeyFile(secondKeyFile); err != nil {
+		t.Fatalf("RotateKeyFile failed: %s", err)
+	}
+	if _, err = NewEncryptedFileCredentialStoreWithPassphrase(path, "store passphrase"); err == nil {
+		t.Errorf("old passphrase still opens the store after rotating to a key file")
+	}
+	if _, err = NewEncryptedFileCredentialStoreWithKeyFile(path, firstKeyFile); err == nil {
+		t.Errorf("first key file opens the store after rotating to the second one")
+	}
+	withKeyFile, err := NewEncryptedFileCredentialStoreWithKeyFile(path, secondKeyFile)
+	if err != nil {
+		t.Fatalf("opening the store with the new key file failed: %s", err)
+	}
+	assertTestCredentialLookup(t, withKeyFile, "Home", NetworkTypePSK, "home passphrase")
+}
+
+func TestEncryptedFileCredentialStoreWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "credentials.json")
+	keyFile := newTestCredentialKeyFile(t, dir, "key")
+	s, err := NewEncryptedFileCredentialStoreWithPassphrase(path, "store passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Add(CredentialEntry{SSID: "Home", Credentials: Credentials{Passphrase: "home passphrase"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewEncryptedFileCredentialStoreWithPassphrase(path, "wrong passphrase"); err == nil {
+		t.Errorf("wrong passphrase opened the store")
+	}
+	if _, err = NewEncryptedFileCredentialStoreWithKeyFile(path, keyFile); err == nil {
+		t.Errorf("key file opened a passphrase protected store")
+	}
+	if err = os.WriteFile(keyFile, []byte("not a key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewEncryptedFileCredentialStoreWithKeyFile(filepath.Join(dir, "other.json"), keyFile); err == nil {
+		t.Errorf("malformed key file was accepted")
+	}
+}
+
+func TestEncryptedFileCredentialStoreTampered(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "credentials.json")
+	keyFile := newTestCredentialKeyFile(t, dir, "key")
+	s, err := NewEncryptedFileCredentialStoreWithKeyFile(path, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Add(CredentialEntry{SSID: "Home", Credentials: Credentials{Passphrase: "home passphrase"}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tamper := []func(*encryptedCredentialEnvelope){
+		func(e *encryptedCredentialEnvelope) { e.Ciphertext[0] ^= 0x01 },
+		func(e *encryptedCredentialEnvelope) { e.Ciphertext[len(e.Ciphertext)-1] ^= 0x80 },
+		func(e *encryptedCredentialEnvelope) { e.Nonce[0] ^= 0x01 },
+		func(e *encryptedCredentialEnvelope) { e.Ciphertext = e.Ciphertext[:len(e.Ciphertext)-1] },
+	}
+	for i, modify := range tamper {
+		var envelope encryptedCredentialEnvelope
+		if err = json.Unmarshal(data, &envelope); err != nil {
+			t.Fatal(err)
+		}
+		modify(&envelope)
+		tampered, err2 := json.Marshal(&envelope)
+		if err2 != nil {
+			t.Fatal(err2)
+		}
+		if err = os.WriteFile(path, tampered, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = s.Lookup("Home", ""); err == nil {
+			t.Errorf("tampered store %d was decrypted", i)
+		}
+	}
+}
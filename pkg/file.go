@@ -0,0 +1,42 @@
+package spider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file in %s: %s", dir, err)
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+	if err = tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to set permissions on %s: %s", tmpPath, err)
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %s", tmpPath, err)
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync %s: %s", tmpPath, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %s", tmpPath, err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %s", tmpPath, path, err)
+	}
+	if d, err2 := os.Open(dir); err2 == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
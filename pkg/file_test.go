@@ -0,0 +1,95 @@
+package spider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func assertNoTemporaryFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp") {
+			t.Errorf("temporary file %s left in %s", entry.Name(), dir)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "profile.psk")
+	for _, perm := range []os.FileMode{0600, 0644} {
+		if err := writeFileAtomic(path, []byte("[Security]\n"), perm); err != nil {
+			t.Fatalf("writeFileAtomic failed: %s", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != perm {
+			t.Errorf("mode = %o; want %o", info.Mode().Perm(), perm)
+		}
+	}
+	assertNoTemporaryFiles(t, dir)
+}
+
+func TestWriteFileAtomicReplacesExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "profile.psk")
+	if err := os.WriteFile(path, []byte("old contents that are longer than the new ones\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = writeFileAtomic(path, []byte("new\n"), 0600); err != nil {
+		t.Fatalf("writeFileAtomic failed: %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new\n" {
+		t.Errorf("contents = %q; want %q", data, "new\n")
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Mode().Perm() != 0600 {
+		t.Errorf("mode = %o; want 600", after.Mode().Perm())
+	}
+	if before.Sys().(*syscall.Stat_t).Ino == after.Sys().(*syscall.Stat_t).Ino {
+		t.Errorf("file was rewritten in place instead of replaced")
+	}
+	assertNoTemporaryFiles(t, dir)
+}
+
+func TestWriteFileAtomicCleansUpOnError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "profile.psk")
+	if err := os.Mkdir(path, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "keep"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("data"), 0600); err == nil {
+		t.Fatal("writeFileAtomic over a non-empty directory succeeded")
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		t.Errorf("target directory was replaced: %v", err)
+	}
+	assertNoTemporaryFiles(t, dir)
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "profile.psk"), []byte("data"), 0600); err == nil {
+		t.Error("writeFileAtomic into a missing directory succeeded")
+	}
+}
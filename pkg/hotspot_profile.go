@@ -0,0 +1,322 @@
+package spider
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	NetworkTypeHotspot      = "hotspot"
+	HotspotDirectoryName    = "hotspot"
+	hotspotProfileExtension = ".conf"
+	profileSectionHotspot   = "Hotspot"
+)
+
+type HotspotProfile struct {
+	ID                string
+	Name              string
+	Domain            *string
+	HESSID            *string
+	NAIRealmNames     []string
+	RoamingConsortium *string
+	Security          NetworkProfileSecurity
+	Settings          NetworkProfileSettings
+	doc               *iniDocument
+}
+
+func NewHotspotProfile(id, name string) *HotspotProfile {
+	return &HotspotProfile{
+		ID:   id,
+		Name: name,
+	}
+}
+
+func (p *HotspotProfile) String() string {
+	return fmt.Sprintf("{ID: %s, Name: %s}", p.ID, p.Name)
+}
+
+func (p *HotspotProfile) FileName() (string, error) {
+	if p.ID == "" || p.ID == "." || p.ID == ".." || strings.ContainsAny(p.ID, "/\x00") {
+		return "", fmt.Errorf("%w: invalid hotspot profile ID %q", ErrInvalidNetworkProfile, p.ID)
+	}
+	return p.ID + hotspotProfileExtension, nil
+}
+
+func (p *HotspotProfile) Validate() error {
+	if _, err := p.FileName(); err != nil {
+		return err
+	}
+	if p.Name == "" {
+		return fmt.Errorf("%w: hotspot profile %s requires a Name", ErrInvalidNetworkProfile, p.ID)
+	}
+	if p.Domain == nil && p.HESSID == nil && len(p.NAIRealmNames) == 0 && p.RoamingConsortium == nil {
+		return fmt.Errorf("%w: hotspot profile %s requires a Domain, HESSID, NAIRealmNames or RoamingConsortium", ErrInvalidNetworkProfile, p.ID)
+	}
+	if p.HESSID != nil {
+		if err := validateHESSID(*p.HESSID); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidNetworkProfile, err)
+		}
+	}
+	if p.RoamingConsortium != nil {
+		if err := validateRoamingConsortium(*p.RoamingConsortium); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidNetworkProfile, err)
+		}
+	}
+	if p.Security.Passphrase != nil || p.Security.PreSharedKey != nil || p.Security.SAEPTGroup19 != nil || p.Security.SAEPTGroup20 != nil {
+		return fmt.Errorf("%w: hotspot profile %s only supports EAP security settings", ErrInvalidNetworkProfile, p.ID)
+	}
+	if _, ok := p.Security.EAP["EAP-Method"]; !ok {
+		return fmt.Errorf("%w: hotspot profile %s requires an EAP-Method", ErrInvalidNetworkProfile, p.ID)
+	}
+	for key, value := range p.Security.EAP {
+		if err := validateEAPSetting(key, value); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidNetworkProfile, err)
+		}
+	}
+	return nil
+}
+
+func (p *HotspotProfile) Marshal() ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if p.doc == nil {
+		p.doc = newIniDocument()
+	}
+	d := p.doc
+
+	d.set(profileSectionHotspot, "Name", p.Name)
+	setOptionalString(d, profileSectionHotspot, "Domain", p.Domain)
+	setOptionalString(d, profileSectionHotspot, "HESSID", p.HESSID)
+	setOptionalCommaList(d, profileSectionHotspot, "NAIRealmNames", p.NAIRealmNames)
+	setOptionalString(d, profileSectionHotspot, "RoamingConsortium", p.RoamingConsortium)
+
+	setEAPSettings(d, p.Security.EAP)
+
+	setOptionalBool(d, profileSectionSettings, "AutoConnect", p.Settings.AutoConnect)
+	setOptionalBool(d, profileSectionSettings, "AlwaysRandomizeAddress", p.Settings.AlwaysRandomizeAddress)
+	setOptionalString(d, profileSectionSettings, "AddressOverride", p.Settings.AddressOverride)
+	return []byte(d.String()), nil
+}
+
+func ParseHotspotProfile(id string, data []byte) (*HotspotProfile, error) {
+	d, err := parseIniDocument(data)
+	if err != nil {
+		log.Errorf("failed to parse hotspot profile %s: %s", id, err)
+		return nil, err
+	}
+	p := NewHotspotProfile(id, "")
+	p.doc = d
+	settings := NewNetworkProfile(id, NetworkType8021X)
+	for _, section := range d.sections {
+		for _, e := range section.entries {
+			if !e.isKey() {
+				continue
+			}
+			switch section.name {
+			case profileSectionHotspot:
+				err = p.parseHotspotEntry(e)
+			case profileSectionSecurity:
+				if !strings.HasPrefix(e.key, "EAP-") {
+					err = &IniParseError{Line: e.line, Message: fmt.Sprintf("[%s] %s: hotspot profiles only support EAP security settings", section.name, e.key)}
+					break
+				}
+				err = settings.parseEntry(section.name, e)
+			case profileSectionSettings:
+				err = settings.parseEntry(section.name, e)
+			}
+			if err != nil {
+				log.Errorf("failed to parse hotspot profile %s: %s", id, err)
+				return nil, err
+			}
+		}
+	}
+	p.Security = settings.Security
+	p.Settings = settings.Settings
+	if p.Name == "" {
+		return nil, fmt.Errorf("%w: hotspot profile %s is missing [Hotspot] Name", ErrInvalidNetworkProfile, id)
+	}
+	return p, nil
+}
+
+func (p *HotspotProfile) parseHotspotEntry(e *iniEntry) error {
+	value := e.value
+	switch e.key {
+	case "Name":
+		p.Name = value
+	case "Domain":
+		p.Domain = &value
+	case "HESSID":
+		if err := validateHESSID(value); err != nil {
+			return &IniParseError{Line: e.line, Message: fmt.Sprintf("[%s] %s: %s", profileSectionHotspot, e.key, err)}
+		}
+		p.HESSID = &value
+	case "NAIRealmNames":
+		p.NAIRealmNames = splitCommaList(value)
+	case "RoamingConsortium":
+		if err := validateRoamingConsortium(value); err != nil {
+			return &IniParseError{Line: e.line, Message: fmt.Sprintf("[%s] %s: %s", profileSectionHotspot, e.key, err)}
+		}
+		p.RoamingConsortium = &value
+	}
+	return nil
+}
+
+func HotspotDirectory(stateDir string) string {
+	if stateDir == "" {
+		stateDir = DefaultStateDirectory
+	}
+	return filepath.Join(stateDir, HotspotDirectoryName)
+}
+
+func LoadHotspotProfile(path string) (*HotspotProfile, error) {
+	base := filepath.Base(path)
+	if filepath.Ext(base) != hotspotProfileExtension {
+		return nil, fmt.Errorf("%w: hotspot profile %s must have a %s extension", ErrInvalidNetworkProfile, path, hotspotProfileExtension)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Errorf("failed to read hotspot profile %s: %s", path, err)
+		return nil, err
+	}
+	p, err := ParseHotspotProfile(strings.TrimSuffix(base, hotspotProfileExtension), data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	log.Debugf("Loaded hotspot profile %s from %s", p, path)
+	return p, nil
+}
+
+func WriteHotspotProfile(stateDir string, profile *HotspotProfile) (string, error) {
+	dir := HotspotDirectory(stateDir)
+	fileName, err := profile.FileName()
+	if err != nil {
+		log.Errorf("failed to get file name for hotspot profile %s: %s", profile.Name, err)
+		return "", err
+	}
+	data, err := profile.Marshal()
+	if err != nil {
+		log.Errorf("failed to marshal hotspot profile %s: %s", profile.Name, err)
+		return "", err
+	}
+	if err = os.MkdirAll(dir, networkProfileDirMode); err != nil {
+		log.Errorf("failed to create hotspot directory %s: %s", dir, err)
+		return "", fmt.Errorf("failed to create hotspot directory %s: %s", dir, err)
+	}
+	path := filepath.Join(dir, fileName)
+	if err = writeFileAtomic(path, data, networkProfileMode); err != nil {
+		log.Errorf("failed to write hotspot profile %s: %s", path, err)
+		return "", err
+	}
+	log.Debugf("Wrote hotspot profile %s to %s", profile.Name, path)
+	return path, nil
+}
+
+func ListHotspotProfiles(stateDir string) ([]*HotspotProfile, error) {
+	dir := HotspotDirectory(stateDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []*HotspotProfile{}, nil
+	}
+	if err != nil {
+		log.Errorf("failed to read hotspot directory %s: %s", dir, err)
+		return nil, err
+	}
+	profiles := make([]*HotspotProfile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != hotspotProfileExtension {
+			continue
+		}
+		p, err2 := LoadHotspotProfile(filepath.Join(dir, entry.Name()))
+		if err2 != nil {
+			log.Warnf("Skipping hotspot profile %s: %s", entry.Name(), err2)
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+func LoadKnownNetworkHotspotProfile(stateDir string, kn *KnownNetwork) (*HotspotProfile, error) {
+	netType, err := kn.GetType()
+	if err != nil {
+		return nil, err
+	}
+	if netType != NetworkTypeHotspot {
+		return nil, fmt.Errorf("KnownNetwork %s is of type %s, not %s", kn.GetPath(), netType, NetworkTypeHotspot)
+	}
+	name, err := kn.GetName()
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := ListHotspotProfiles(stateDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no hotspot profile named %s found in %s", name, HotspotDirectory(stateDir))
+}
+
+func LoadNetworkHotspotProfile(stateDir string, n *Network) (*HotspotProfile, error) {
+	netType, err := n.GetType()
+	if err != nil {
+		return nil, err
+	}
+	if netType != NetworkTypeHotspot {
+		return nil, fmt.Errorf("network %s is of type %s, not %s", n.GetPath(), netType, NetworkTypeHotspot)
+	}
+	kn := n.GetKnownNetwork()
+	if kn == nil {
+		return nil, fmt.Errorf("network %s has no provisioned hotspot KnownNetwork", n.GetPath())
+	}
+	return LoadKnownNetworkHotspotProfile(stateDir, kn)
+}
+
+func splitCommaList(value string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func setOptionalCommaList(d *iniDocument, section, key string, values []string) {
+	if len(values) == 0 {
+		d.unset(section, key)
+		return
+	}
+	if e := d.entry(section, key); e != nil && slices.Equal(splitCommaList(e.value), values) {
+		return
+	}
+	d.set(section, key, strings.Join(values, ","))
+}
+
+func validateHESSID(hessid string) error {
+	if mac, err := net.ParseMAC(hessid); err != nil || len(mac) != 6 {
+		return fmt.Errorf("HESSID %q must be a MAC address", hessid)
+	}
+	return nil
+}
+
+func validateRoamingConsortium(oi string) error {
+	if len(oi) != 6 && len(oi) != 10 {
+		return fmt.Errorf("roaming consortium OI %q must be 3 or 5 octets in hex", oi)
+	}
+	if _, err := hex.DecodeString(oi); err != nil {
+		return fmt.Errorf("roaming consortium OI %q must only contain hex digits", oi)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package spider
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+type IniParseError struct {
+	Line    int
+	Message string
+}
+
+func (e *IniParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+type iniEntry struct {
+	key   string
+	value string
+	raw   string
+	line  int
+}
+
+func (e *iniEntry) isKey() bool {
+	return e.key != ""
+}
+
+type iniSection struct {
+	name    string
+	raw     string
+	entries []*iniEntry
+	line    int
+}
+
+type iniDocument struct {
+	preamble            []*iniEntry
+	sections            []*iniSection
+	missingFinalNewline bool
+}
+
+func newIniDocument() *iniDocument {
+	return &iniDocument{}
+}
+
+func parseIniDocument(data []byte) (*iniDocument, error) {
+	d := newIniDocument()
+	var current *iniSection
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			entry := &iniEntry{raw: raw, line: lineNumber}
+			if current == nil {
+				d.preamble = append(d.preamble, entry)
+			} else {
+				current.entries = append(current.entries, entry)
+			}
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") || len(line) < 3 {
+				return nil, &IniParseError{Line: lineNumber, Message: fmt.Sprintf("malformed section header %q", line)}
+			}
+			current = &iniSection{name: line[1 : len(line)-1], raw: raw, line: lineNumber}
+			d.sections = append(d.sections, current)
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				return nil, &IniParseError{Line: lineNumber, Message: fmt.Sprintf("expected key=value, got %q", line)}
+			}
+			if current == nil {
+				return nil, &IniParseError{Line: lineNumber, Message: fmt.Sprintf("key %s outside of a section", key)}
+			}
+			current.entries = append(current.entries, &iniEntry{
+				key:   key,
+				value: strings.TrimSpace(value),
+				raw:   raw,
+				line:  lineNumber,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &IniParseError{Line: lineNumber + 1, Message: err.Error()}
+	}
+	d.missingFinalNewline = len(data) > 0 && data[len(data)-1] != '\n'
+	return d, nil
+}
+
+func (d *iniDocument) section(name string) *iniSection {
+	for _, s := range d.sections {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (d *iniDocument) addSection(name string) *iniSection {
+	if n := len(d.sections); n > 0 {
+		last := d.sections[n-1]
+		if len(last.entries) == 0 || last.entries[len(last.entries)-1].isKey() || strings.TrimSpace(last.entries[len(last.entries)-1].raw) != "" {
+			last.entries = append(last.entries, &iniEntry{})
+		}
+	}
+	s := &iniSection{name: name}
+	d.sections = append(d.sections, s)
+	return s
+}
+
+func (d *iniDocument) get(section, key string) (string, bool) {
+	s := d.section(section)
+	if s == nil {
+		return "", false
+	}
+	for _, e := range s.entries {
+		if e.key == key {
+			return e.value, true
+		}
+	}
+	return "", false
+}
+
+func (d *iniDocument) set(section, key, value string) {
+	s := d.section(section)
+	if s == nil {
+		s = d.addSection(section)
+	}
+	for _, e := range s.entries {
+		if e.key == key {
+			if e.value != value {
+				e.value = value
+				e.raw = ""
+			}
+			return
+		}
+	}
+	i := len(s.entries)
+	for i > 0 && !s.entries[i-1].isKey() && strings.TrimSpace(s.entries[i-1].raw) == "" {
+		i--
+	}
+	s.entries = append(s.entries[:i], append([]*iniEntry{{key: key, value: value}}, s.entries[i:]...)...)
+}
+
+func (d *iniDocument) unset(section, key string) {
+	s := d.section(section)
+	if s == nil {
+		return
+	}
+	entries := s.entries[:0]
+	for _, e := range s.entries {
+		if e.key != key {
+			entries = append(entries, e)
+		}
+	}
+	s.entries = entries
+	d.pruneSection(s)
+}
+
+func (d *iniDocument) entry(section, key string) *iniEntry {
+	s := d.section(section)
+	if s == nil {
+		return nil
+	}
+	for _, e := range s.entries {
+		if e.key == key {
+			return e
+		}
+	}
+	return nil
+}
+
+func (d *iniDocument) keys(section string) []string {
+	s := d.section(section)
+	if s == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(s.entries))
+	for _, e := range s.entries {
+		if e.isKey() {
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
+func (d *iniDocument) pruneSection(s *iniSection) {
+	for _, e := range s.entries {
+		if e.isKey() || strings.TrimSpace(e.raw) != "" {
+			return
+		}
+	}
+	wasLast := len(d.sections) > 0 && d.sections[len(d.sections)-1] == s
+	sections := d.sections[:0]
+	for _, other := range d.sections {
+		if other != s {
+			sections = append(sections, other)
+		}
+	}
+	d.sections = sections
+	if wasLast && len(d.sections) > 0 {
+		last := d.sections[len(d.sections)-1]
+		for len(last.entries) > 0 && !last.entries[len(last.entries)-1].isKey() && strings.TrimSpace(last.entries[len(last.entries)-1].raw) == "" {
+			last.entries = last.entries[:len(last.entries)-1]
+		}
+	}
+}
+
+func (d *iniDocument) String() string {
+	var b strings.Builder
+	writeEntries := func(entries []*iniEntry) {
+		for _, e := range entries {
+			if e.isKey() && e.raw == "" {
+				b.WriteString(e.key)
+				b.WriteString("=")
+				b.WriteString(e.value)
+			} else {
+				b.WriteString(e.raw)
+			}
+			b.WriteString("\n")
+		}
+	}
+	writeEntries(d.preamble)
+	for _, s := range d.sections {
+		if s.raw != "" {
+			b.WriteString(s.raw)
+		} else {
+			b.WriteString("[")
+			b.WriteString(s.name)
+			b.WriteString("]")
+		}
+		b.WriteString("\n")
+		writeEntries(s.entries)
+	}
+	if d.missingFinalNewline {
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package spider
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	busInterface                  = "org.freedesktop.DBus"
+	busMethodGetNameOwner         = busInterface + ".GetNameOwner"
+	busSignalNameOwnerChanged     = busInterface + ".NameOwnerChanged"
+	busErrorAccessDenied          = busInterface + ".Error.AccessDenied"
+	busErrorNameHasNoOwner        = busInterface + ".Error.NameHasNoOwner"
+	busPath                       = "/org/freedesktop/DBus"
+	iwdOwnerTrackerSignalCapacity = 16
+)
+
+type IwdOwnerTracker struct {
+	conn    *dbus.Conn
+	mu      sync.RWMutex
+	owner   string
+	options []dbus.MatchOption
+	signals chan *dbus.Signal
+	done    chan struct{}
+	once    sync.Once
+	logger  *log.Entry
+}
+
+func NewIwdOwnerTracker(conn *dbus.Conn) (*IwdOwnerTracker, error) {
+	log.SetReportCaller(true)
+	t := &IwdOwnerTracker{
+		conn: conn,
+		options: []dbus.MatchOption{
+			dbus.WithMatchSender(busInterface),
+			dbus.WithMatchObjectPath(busPath),
+			dbus.WithMatchInterface(busInterface),
+			dbus.WithMatchMember("NameOwnerChanged"),
+			dbus.WithMatchArg(0, IwdService),
+		},
+		signals: make(chan *dbus.Signal, iwdOwnerTrackerSignalCapacity),
+		done:    make(chan struct{}),
+		logger: log.WithFields(log.Fields{
+			"type":    "IwdOwnerTracker",
+			"service": IwdService,
+		}),
+	}
+	if err := conn.AddMatchSignal(t.options...); err != nil {
+		t.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("failed to add NameOwnerChanged match")
+		return nil, fmt.Errorf("failed to watch owner of %s: %s", IwdService, err)
+	}
+	conn.Signal(t.signals)
+	owner, err := getNameOwner(conn, IwdService)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	t.owner = owner
+	t.logger.Debugf("%s is owned by %q", IwdService, owner)
+	go t.watch()
+	return t, nil
+}
+
+func (t *IwdOwnerTracker) GetOwner() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.owner
+}
+
+func (t *IwdOwnerTracker) Verify(sender dbus.Sender, method string) *dbus.Error {
+	owner := t.GetOwner()
+	if owner != "" && string(sender) == owner {
+		return nil
+	}
+	t.logger.Warnf("Rejected %s call from %s, %s is owned by %q", method, sender, IwdService, owner)
+	return dbus.NewError(busErrorAccessDenied, []interface{}{
+		fmt.Sprintf("%s may only be called by %s", method, IwdService),
+	})
+}
+
+func (t *IwdOwnerTracker) Close() {
+	t.once.Do(func() {
+		t.conn.RemoveSignal(t.signals)
+		_ = t.conn.RemoveMatchSignal(t.options...)
+		close(t.done)
+	})
+}
+
+func (t *IwdOwnerTracker) watch() {
+	for {
+		select {
+		case <-t.done:
+			return
+		case sig, ok := <-t.signals:
+			if !ok {
+				return
+			}
+			if sig.Name != busSignalNameOwnerChanged || len(sig.Body) < 3 {
+				continue
+			}
+			if name, ok := sig.Body[0].(string); !ok || name != IwdService {
+				continue
+			}
+			owner, ok := sig.Body[2].(string)
+			if !ok {
+				continue
+			}
+			t.mu.Lock()
+			t.owner = owner
+			t.mu.Unlock()
+			t.logger.Infof("%s owner changed to %q", IwdService, owner)
+		}
+	}
+}
+
+func getNameOwner(conn *dbus.Conn, name string) (string, error) {
+	var owner string
+	err := conn.BusObject().Call(busMethodGetNameOwner, 0, name).Store(&owner)
+	if err != nil {
+		if dbusErr, ok := err.(dbus.Error); ok && dbusErr.Name == busErrorNameHasNoOwner {
+			return "", nil
+		}
+		log.Errorf("failed to get owner of %s: %s", name, err)
+		return "", fmt.Errorf("failed to get owner of %s: %s", name, err)
+	}
+	return owner, nil
+}
@@ -0,0 +1,63 @@
+package spider
+
+import (
+	"testing"
+)
+
+func TestAgentServerRejectsNonOwner(t *testing.T) {
+	client, server := newTestConnPair(t)
+	bus, _ := exportTestAgentManager(t, server, nil)
+	s, _ := serveTestAgent(t, client, newScopedAgent(client, testNetworkPath, Credentials{Passphrase: "password"}))
+
+	call := callTestAs(t, server, ":1.99", s.GetPath(), AgentInterface+".RequestPassphrase", testNetworkPath)
+	assertTestDBusError(t, "RequestPassphrase from a non-owner", call.Err, busErrorAccessDenied)
+	call = callTestAs(t, server, ":1.99", s.GetPath(), AgentInterface+".Release")
+	assertTestDBusError(t, "Release from a non-owner", call.Err, busErrorAccessDenied)
+	select {
+	case <-s.Done():
+		t.Fatal("Release from a non-owner stopped the agent server")
+	default:
+	}
+
+	bus.setOwner(t, IwdService, ":1.2")
+	waitForTestCondition(t, "iwd owner to change", func() bool {
+		return s.owner.GetOwner() == ":1.2"
+	})
+	call = callTestAs(t, server, testIwdOwner, s.GetPath(), AgentInterface+".RequestPassphrase", testNetworkPath)
+	assertTestDBusError(t, "RequestPassphrase from the previous owner", call.Err, busErrorAccessDenied)
+	call = callTestAs(t, server, ":1.2", s.GetPath(), AgentInterface+".RequestPassphrase", testNetworkPath)
+	if call.Err != nil {
+		t.Errorf("RequestPassphrase from the new owner failed: %s", call.Err)
+	}
+
+	bus.setOwner(t, IwdService, "")
+	waitForTestCondition(t, "iwd to lose its owner", func() bool {
+		return s.owner.GetOwner() == ""
+	})
+	call = callTestAs(t, server, "", s.GetPath(), AgentInterface+".RequestPassphrase", testNetworkPath)
+	assertTestDBusError(t, "RequestPassphrase without an owner", call.Err, busErrorAccessDenied)
+}
+
+func TestNewIwdOwnerTracker(t *testing.T) {
+	client, server := newTestConnPair(t)
+	bus := exportTestBus(t, server)
+	tracker, err := NewIwdOwnerTracker(client)
+	if err != nil {
+		t.Fatalf("NewIwdOwnerTracker failed: %s", err)
+	}
+	defer tracker.Close()
+	if owner := tracker.GetOwner(); owner != "" {
+		t.Errorf("owner without iwd = %q; want none", owner)
+	}
+	bus.setOwner(t, "org.example.Other", ":1.5")
+	bus.setOwner(t, IwdService, ":1.7")
+	waitForTestCondition(t, "iwd owner to change", func() bool {
+		return tracker.GetOwner() == ":1.7"
+	})
+	if err := tracker.Verify(":1.5", "Test"); err == nil || err.Name != busErrorAccessDenied {
+		t.Errorf("Verify for another name's owner = %v; want %s", err, busErrorAccessDenied)
+	}
+	if err := tracker.Verify(":1.7", "Test"); err != nil {
+		t.Errorf("Verify for the owner failed: %s", err)
+	}
+}
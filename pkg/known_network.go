@@ -3,6 +3,8 @@ package spider
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 	log "github.com/sirupsen/logrus"
@@ -29,7 +31,7 @@ type KnownNetworker interface {
 	GetName() (string, error)
 	GetType() (string, error)
 	GetHidden() (bool, error)
-	GetLastConnectedTime() (string, error)
+	GetLastConnectedTime() (*time.Time, error)
 	GetAutoConnect() (bool, error)
 	SetAutoConnect(autoConnect bool) error
 	Forget() error
@@ -42,9 +44,13 @@ type KnownNetwork struct {
 	name              string
 	netType           string
 	hidden            bool
-	lastConnectedTime *string
-	autoConnect       bool
-	forgotten         bool
+	lastConnectedTime *time.Time
+	// lastConnectedTimeErr is set when iwd reported a LastConnectedTime that
+	// could not be parsed, so the network cannot be told apart from one that
+	// was never connected.
+	lastConnectedTimeErr error
+	autoConnect          bool
+	forgotten            atomic.Bool
 }
 
 func NewKnownNetwork(conn *dbus.Conn, path dbus.ObjectPath) (*KnownNetwork, error) {
@@ -55,10 +61,9 @@ func NewKnownNetwork(conn *dbus.Conn, path dbus.ObjectPath) (*KnownNetwork, erro
 	})
 	obj := conn.Object(IwdService, path)
 	kn := &KnownNetwork{
-		conn:      conn,
-		obj:       obj,
-		path:      path,
-		forgotten: false,
+		conn: conn,
+		obj:  obj,
+		path: path,
 	}
 	if variant, err := kn.obj.GetProperty(knownNetworkPropertyName); err != nil {
 		knLogger.WithFields(log.Fields{
@@ -108,13 +113,23 @@ func NewKnownNetwork(conn *dbus.Conn, path dbus.ObjectPath) (*KnownNetwork, erro
 		}).Info("Failed to get optional property 'last connected time'")
 		kn.lastConnectedTime = nil
 	} else {
-		if err2 := variant.Store(&kn.lastConnectedTime); err2 != nil {
+		var lastConnectedTime string
+		if err2 := variant.Store(&lastConnectedTime); err2 != nil {
 			knLogger.WithFields(log.Fields{
 				"err": err2,
 			}).Error("Failed to store property 'last connected time'")
 			return nil, err2
 		}
-		knLogger.Debugf("Last Connected Time = %s", *kn.lastConnectedTime)
+		if t, err2 := time.Parse(time.RFC3339, lastConnectedTime); err2 != nil {
+			knLogger.WithFields(log.Fields{
+				"err": err2,
+			}).Warnf("Failed to parse optional property 'last connected time' %s", lastConnectedTime)
+			kn.lastConnectedTime = nil
+			kn.lastConnectedTimeErr = fmt.Errorf("failed to parse last connected time %q: %s", lastConnectedTime, err2)
+		} else {
+			kn.lastConnectedTime = &t
+			knLogger.Debugf("Last Connected Time = %s", kn.lastConnectedTime)
+		}
 	}
 	if variant, err := kn.obj.GetProperty(knownNetworkPropertyAutoConnect); err != nil {
 		knLogger.WithFields(log.Fields{
@@ -142,7 +157,7 @@ func (kn *KnownNetwork) GetInterface() string {
 }
 
 func (kn *KnownNetwork) GetName() (string, error) {
-	if kn.forgotten {
+	if kn.forgotten.Load() {
 		knLogger.WithFields(log.Fields{
 			"err": ErrNetworkForgotten,
 		}).Errorf("Network %s has been forgotten", kn.name)
@@ -153,7 +168,7 @@ func (kn *KnownNetwork) GetName() (string, error) {
 }
 
 func (kn *KnownNetwork) GetType() (string, error) {
-	if kn.forgotten {
+	if kn.forgotten.Load() {
 		return "", ErrNetworkForgotten
 	}
 	knLogger.Debugf("GetType %s", kn.netType)
@@ -161,7 +176,7 @@ func (kn *KnownNetwork) GetType() (string, error) {
 }
 
 func (kn *KnownNetwork) GetHidden() (bool, error) {
-	if kn.forgotten {
+	if kn.forgotten.Load() {
 		knLogger.WithFields(log.Fields{
 			"err": ErrNetworkForgotten,
 		}).Errorf("Network %s has been forgotten", kn.name)
@@ -171,15 +186,15 @@ func (kn *KnownNetwork) GetHidden() (bool, error) {
 	return kn.hidden, nil
 }
 
-func (kn *KnownNetwork) GetLastConnectedTime() (*string, error) {
-	if kn.forgotten {
+func (kn *KnownNetwork) GetLastConnectedTime() (*time.Time, error) {
+	if kn.forgotten.Load() {
 		knLogger.WithFields(log.Fields{
 			"err": ErrNetworkForgotten,
 		}).Errorf("Network %s has been forgotten", kn.name)
 		return nil, ErrNetworkForgotten
 	}
 	if kn.lastConnectedTime != nil {
-		knLogger.Debugf("GetLastConnectedTime %s", *kn.lastConnectedTime)
+		knLogger.Debugf("GetLastConnectedTime %s", kn.lastConnectedTime)
 	} else {
 		knLogger.Debugf("No LastConnectedTime")
 	}
@@ -187,7 +202,7 @@ func (kn *KnownNetwork) GetLastConnectedTime() (*string, error) {
 }
 
 func (kn *KnownNetwork) GetAutoConnect() (bool, error) {
-	if kn.forgotten {
+	if kn.forgotten.Load() {
 		knLogger.WithFields(log.Fields{
 			"err": ErrNetworkForgotten,
 		}).Errorf("Network %s has been forgotten", kn.name)
@@ -198,7 +213,7 @@ func (kn *KnownNetwork) GetAutoConnect() (bool, error) {
 }
 
 func (kn *KnownNetwork) SetAutoConnect(autoConnect bool) error {
-	if kn.forgotten {
+	if kn.forgotten.Load() {
 		knLogger.WithFields(log.Fields{
 			"err": ErrNetworkForgotten,
 		}).Errorf("Network %s has been forgotten", kn.name)
@@ -222,13 +237,13 @@ func (kn *KnownNetwork) String() string {
 	if kn.lastConnectedTime == nil {
 		lastConnectedTime = "<nil>"
 	} else {
-		lastConnectedTime = *kn.lastConnectedTime
+		lastConnectedTime = kn.lastConnectedTime.Format(time.RFC3339)
 	}
 	return fmt.Sprintf("{Path: %s, Interface: %s, Name: %s, Type: %s, Hidden: %t, LastConnectedTime: %s, AutoConnect: %t}", kn.path, kn.GetInterface(), kn.name, kn.netType, kn.hidden, lastConnectedTime, kn.autoConnect)
 }
 
 func (kn *KnownNetwork) Forget() error {
-	if kn.forgotten {
+	if kn.forgotten.Load() {
 		knLogger.WithFields(log.Fields{
 			"err": ErrNetworkForgotten,
 		}).Errorf("Network %s has been forgotten", kn.name)
@@ -241,6 +256,6 @@ func (kn *KnownNetwork) Forget() error {
 		return err
 	}
 	knLogger.Debugf("Forgotten")
-	kn.forgotten = true
+	kn.forgotten.Store(true)
 	return nil
 }
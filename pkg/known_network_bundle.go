@@ -0,0 +1,313 @@
+package spider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	KnownNetworkBundleVersion = 1
+	knownNetworkBundleAD      = "spider-known-network-bundle"
+)
+
+var (
+	ErrBundlePassphraseRequired = errors.New("bundle is encrypted and requires a passphrase")
+)
+
+type ImportConflict uint8
+
+const (
+	ImportConflictSkip ImportConflict = iota
+	ImportConflictOverwrite
+	// ImportConflictRename moves the existing profile to
+	// <BackupDirectory>/<file>.<unix>.bak, outside of the directory iwd scans,
+	// and then writes the imported profile in its place.
+	ImportConflictRename
+)
+
+type ImportAction string
+
+const (
+	ImportActionCreate    ImportAction = "create"
+	ImportActionSkip      ImportAction = "skip"
+	ImportActionOverwrite ImportAction = "overwrite"
+	ImportActionRename    ImportAction = "rename"
+)
+
+type KnownNetworkBundleEntry struct {
+	Name              string     `json:"name"`
+	Type              string     `json:"type"`
+	Hidden            bool       `json:"hidden"`
+	AutoConnect       bool       `json:"autoconnect"`
+	LastConnectedTime *time.Time `json:"last_connected_time,omitempty"`
+	Profile           string     `json:"profile"`
+}
+
+type KnownNetworkBundle struct {
+	Version  int                       `json:"version"`
+	Created  time.Time                 `json:"created"`
+	Networks []KnownNetworkBundleEntry `json:"networks"`
+}
+
+type knownNetworkBundleEnvelope struct {
+	Version    int                 `json:"version"`
+	Encrypted  bool                `json:"encrypted"`
+	KDF        *keyDerivation      `json:"kdf,omitempty"`
+	Nonce      []byte              `json:"nonce,omitempty"`
+	Ciphertext []byte              `json:"ciphertext,omitempty"`
+	Bundle     *KnownNetworkBundle `json:"bundle,omitempty"`
+}
+
+type ImportOptions struct {
+	Conflict        ImportConflict
+	BackupDirectory string
+	DryRun          bool
+}
+
+type ImportResult struct {
+	Name       string
+	Type       string
+	Path       string
+	Action     ImportAction
+	BackupPath string
+}
+
+type ImportReport struct {
+	DryRun  bool
+	Results []ImportResult
+}
+
+func (r *ImportReport) String() string {
+	return fmt.Sprintf("{DryRun: %t, Results: %v}", r.DryRun, r.Results)
+}
+
+func ExportKnownNetworks(conn *dbus.Conn, stateDir string) (*KnownNetworkBundle, error) {
+	if stateDir == "" {
+		stateDir = DefaultStateDirectory
+	}
+	knownNetworks, err := GetKnownNetworks(conn)
+	if err != nil {
+		log.Errorf("failed to get KnownNetworks for export: %s", err)
+		return nil, err
+	}
+	bundle := &KnownNetworkBundle{
+		Version:  KnownNetworkBundleVersion,
+		Created:  time.Now().UTC(),
+		Networks: make([]KnownNetworkBundleEntry, 0, len(knownNetworks)),
+	}
+	for _, kn := range knownNetworks {
+		path, err2 := KnownNetworkProfilePath(stateDir, kn)
+		if err2 != nil {
+			log.Warnf("Skipping export of KnownNetwork %s: %s", kn.GetPath(), err2)
+			continue
+		}
+		profile, err2 := os.ReadFile(path)
+		if err2 != nil {
+			log.Errorf("failed to read profile %s for export: %s", path, err2)
+			return nil, fmt.Errorf("failed to read profile %s: %s", path, err2)
+		}
+		entry := KnownNetworkBundleEntry{
+			Name:        kn.name,
+			Type:        kn.netType,
+			Hidden:      kn.hidden,
+			AutoConnect: kn.autoConnect,
+			Profile:     string(profile),
+		}
+		if kn.lastConnectedTime != nil {
+			lastConnectedTime := *kn.lastConnectedTime
+			entry.LastConnectedTime = &lastConnectedTime
+		}
+		bundle.Networks = append(bundle.Networks, entry)
+		log.Debugf("Exported KnownNetwork %s", kn.name)
+	}
+	return bundle, nil
+}
+
+func (b *KnownNetworkBundle) Marshal(passphrase string) ([]byte, error) {
+	envelope := &knownNetworkBundleEnvelope{
+		Version: KnownNetworkBundleVersion,
+	}
+	if passphrase == "" {
+		envelope.Bundle = b
+		return json.MarshalIndent(envelope, "", "  ")
+	}
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %s", err)
+	}
+	kdf, err := newKeyDerivation()
+	if err != nil {
+		return nil, err
+	}
+	key, err := kdf.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce, ciphertext, err := sealAESGCM(key, plaintext, []byte(knownNetworkBundleAD))
+	if err != nil {
+		return nil, err
+	}
+	envelope.Encrypted = true
+	envelope.KDF = kdf
+	envelope.Nonce = nonce
+	envelope.Ciphertext = ciphertext
+	return json.MarshalIndent(envelope, "", "  ")
+}
+
+func UnmarshalKnownNetworkBundle(data []byte, passphrase string) (*KnownNetworkBundle, error) {
+	var envelope knownNetworkBundleEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bundle: %s", err)
+	}
+	if envelope.Version != KnownNetworkBundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", envelope.Version)
+	}
+	if !envelope.Encrypted {
+		if envelope.Bundle == nil {
+			return nil, fmt.Errorf("bundle is empty")
+		}
+		return envelope.Bundle, nil
+	}
+	if passphrase == "" {
+		return nil, ErrBundlePassphraseRequired
+	}
+	if envelope.KDF == nil {
+		return nil, fmt.Errorf("encrypted bundle is missing key derivation parameters")
+	}
+	key, err := envelope.KDF.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := openAESGCM(key, envelope.Nonce, envelope.Ciphertext, []byte(knownNetworkBundleAD))
+	if err != nil {
+		return nil, err
+	}
+	var bundle KnownNetworkBundle
+	if err = json.Unmarshal(plaintext, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal decrypted bundle: %s", err)
+	}
+	return &bundle, nil
+}
+
+func ImportKnownNetworks(stateDir string, bundle *KnownNetworkBundle, options ImportOptions) (*ImportReport, error) {
+	if stateDir == "" {
+		stateDir = DefaultStateDirectory
+	}
+	if bundle.Version != KnownNetworkBundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", bundle.Version)
+	}
+	if options.Conflict == ImportConflictRename {
+		if err := validateBackupDirectory(stateDir, options.BackupDirectory); err != nil {
+			return nil, err
+		}
+	}
+	report := &ImportReport{
+		DryRun:  options.DryRun,
+		Results: make([]ImportResult, 0, len(bundle.Networks)),
+	}
+	for _, entry := range bundle.Networks {
+		profile, err := ParseNetworkProfile(entry.Name, entry.Type, []byte(entry.Profile))
+		if err != nil {
+			log.Errorf("failed to parse bundled profile for %s: %s", entry.Name, err)
+			return report, fmt.Errorf("invalid profile for %s: %w", entry.Name, err)
+		}
+		if entry.Hidden || profile.Settings.Hidden != nil {
+			hidden := entry.Hidden
+			profile.Settings.Hidden = &hidden
+		}
+		if !entry.AutoConnect || profile.Settings.AutoConnect != nil {
+			autoConnect := entry.AutoConnect
+			profile.Settings.AutoConnect = &autoConnect
+		}
+		fileName, err := profile.FileName()
+		if err != nil {
+			return report, err
+		}
+		result := ImportResult{
+			Name:   entry.Name,
+			Type:   entry.Type,
+			Path:   filepath.Join(stateDir, fileName),
+			Action: ImportActionCreate,
+		}
+		if _, err = os.Stat(result.Path); err == nil {
+			switch options.Conflict {
+			case ImportConflictOverwrite:
+				result.Action = ImportActionOverwrite
+			case ImportConflictRename:
+				result.Action = ImportActionRename
+				result.BackupPath = filepath.Join(options.BackupDirectory, fmt.Sprintf("%s.%d.bak", fileName, time.Now().Unix()))
+			default:
+				result.Action = ImportActionSkip
+			}
+		} else if !os.IsNotExist(err) {
+			log.Errorf("failed to stat %s: %s", result.Path, err)
+			return report, err
+		}
+		if !options.DryRun {
+			if result.Action == ImportActionRename {
+				if err = backupNetworkProfile(result.Path, result.BackupPath); err != nil {
+					log.Errorf("failed to back up %s to %s: %s", result.Path, result.BackupPath, err)
+					return report, err
+				}
+			}
+			if result.Action != ImportActionSkip {
+				if _, err = WriteNetworkProfile(stateDir, profile); err != nil {
+					return report, err
+				}
+				if entry.LastConnectedTime != nil {
+					if err = os.Chtimes(result.Path, *entry.LastConnectedTime, *entry.LastConnectedTime); err != nil {
+						log.Errorf("failed to restore last connected time of %s: %s", result.Path, err)
+						return report, fmt.Errorf("failed to restore last connected time of %s: %s", result.Path, err)
+					}
+				}
+			}
+		}
+		log.Debugf("Import %s %s (dry run: %t)", result.Action, result.Path, options.DryRun)
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+func validateBackupDirectory(stateDir, backupDir string) error {
+	if backupDir == "" {
+		return fmt.Errorf("renaming conflicting profiles requires a backup directory")
+	}
+	stateDir, err := filepath.Abs(stateDir)
+	if err != nil {
+		return err
+	}
+	backupDir, err = filepath.Abs(backupDir)
+	if err != nil {
+		return err
+	}
+	if rel, err2 := filepath.Rel(stateDir, backupDir); err2 == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("backup directory %s must be outside of the state directory %s", backupDir, stateDir)
+	}
+	return nil
+}
+
+func backupNetworkProfile(path, backupPath string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(backupPath), networkProfileDirMode); err != nil {
+		return err
+	}
+	if err = writeFileAtomic(backupPath, data, networkProfileMode); err != nil {
+		return err
+	}
+	return os.Chtimes(backupPath, info.ModTime(), info.ModTime())
+}
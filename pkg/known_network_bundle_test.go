@@ -0,0 +1,163 @@
+package spider
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKnownNetworkBundle() *KnownNetworkBundle {
+	lastConnectedTime := time.Date(2024, 5, 17, 8, 30, 0, 0, time.UTC)
+	return &KnownNetworkBundle{
+		Version: KnownNetworkBundleVersion,
+		Created: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		Networks: []KnownNetworkBundleEntry{
+			{
+				Name:              "Home",
+				Type:              NetworkTypePSK,
+				AutoConnect:       true,
+				LastConnectedTime: &lastConnectedTime,
+				Profile:           "[Security]\nPassphrase=imported\n",
+			},
+		},
+	}
+}
+
+func TestKnownNetworkBundleMarshal(t *testing.T) {
+	bundle := testKnownNetworkBundle()
+	for _, passphrase := range []string{"", "bundle passphrase"} {
+		data, err := bundle.Marshal(passphrase)
+		if err != nil {
+			t.Fatalf("Marshal failed: %s", err)
+		}
+		if passphrase != "" && strings.Contains(string(data), "imported") {
+			t.Errorf("encrypted bundle contains the plaintext profile:\n%s", data)
+		}
+		decoded, err := UnmarshalKnownNetworkBundle(data, passphrase)
+		if err != nil {
+			t.Fatalf("UnmarshalKnownNetworkBundle failed: %s", err)
+		}
+		if len(decoded.Networks) != 1 || decoded.Networks[0].Profile != bundle.Networks[0].Profile ||
+			!decoded.Networks[0].LastConnectedTime.Equal(*bundle.Networks[0].LastConnectedTime) {
+			t.Errorf("round trip with passphrase %q = %+v; want %+v", passphrase, decoded.Networks, bundle.Networks)
+		}
+	}
+}
+
+func TestKnownNetworkBundleWrongPassphrase(t *testing.T) {
+	data, err := testKnownNetworkBundle().Marshal("bundle passphrase")
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	if _, err = UnmarshalKnownNetworkBundle(data, ""); !errors.Is(err, ErrBundlePassphraseRequired) {
+		t.Errorf("UnmarshalKnownNetworkBundle without passphrase error = %v; want %v", err, ErrBundlePassphraseRequired)
+	}
+	if _, err = UnmarshalKnownNetworkBundle(data, "wrong passphrase"); err == nil {
+		t.Errorf("UnmarshalKnownNetworkBundle with the wrong passphrase succeeded")
+	}
+}
+
+func TestImportKnownNetworks(t *testing.T) {
+	tests := []struct {
+		name       string
+		conflict   ImportConflict
+		existing   bool
+		dryRun     bool
+		action     ImportAction
+		passphrase string
+	}{
+		{name: "create", conflict: ImportConflictSkip, action: ImportActionCreate, passphrase: "imported"},
+		{name: "skip", conflict: ImportConflictSkip, existing: true, action: ImportActionSkip, passphrase: "existing"},
+		{name: "overwrite", conflict: ImportConflictOverwrite, existing: true, action: ImportActionOverwrite, passphrase: "imported"},
+		{name: "rename", conflict: ImportConflictRename, existing: true, action: ImportActionRename, passphrase: "imported"},
+		{name: "dry run overwrite", conflict: ImportConflictOverwrite, existing: true, dryRun: true, action: ImportActionOverwrite, passphrase: "existing"},
+		{name: "dry run rename", conflict: ImportConflictRename, existing: true, dryRun: true, action: ImportActionRename, passphrase: "existing"},
+		{name: "dry run create", conflict: ImportConflictSkip, dryRun: true, action: ImportActionCreate},
+	}
+	for _, tt := range tests {
+		stateDir := t.TempDir()
+		backupDir := t.TempDir()
+		path := filepath.Join(stateDir, "Home.psk")
+		if tt.existing {
+			if err := os.WriteFile(path, []byte("[Security]\nPassphrase=existing\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		bundle := testKnownNetworkBundle()
+		report, err := ImportKnownNetworks(stateDir, bundle, ImportOptions{
+			Conflict:        tt.conflict,
+			BackupDirectory: backupDir,
+			DryRun:          tt.dryRun,
+		})
+		if err != nil {
+			t.Fatalf("%s: ImportKnownNetworks failed: %s", tt.name, err)
+		}
+		if report.DryRun != tt.dryRun || len(report.Results) != 1 {
+			t.Fatalf("%s: report = %s", tt.name, report)
+		}
+		result := report.Results[0]
+		if result.Action != tt.action || result.Path != path {
+			t.Errorf("%s: result = %+v; want action %s for %s", tt.name, result, tt.action, path)
+		}
+		if tt.passphrase == "" {
+			if _, err = os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("%s: dry run created %s", tt.name, path)
+			}
+		} else if profile, err2 := LoadNetworkProfile(path); err2 != nil {
+			t.Errorf("%s: failed to load %s: %s", tt.name, path, err2)
+		} else if *profile.Security.Passphrase != tt.passphrase {
+			t.Errorf("%s: Passphrase = %s; want %s", tt.name, *profile.Security.Passphrase, tt.passphrase)
+		}
+		if tt.passphrase == "imported" {
+			info, err2 := os.Stat(path)
+			if err2 != nil {
+				t.Fatal(err2)
+			}
+			if !info.ModTime().Equal(*bundle.Networks[0].LastConnectedTime) {
+				t.Errorf("%s: modification time = %s; want the last connected time %s", tt.name, info.ModTime(), bundle.Networks[0].LastConnectedTime)
+			}
+		}
+		if tt.action == ImportActionRename {
+			if filepath.Dir(result.BackupPath) != backupDir {
+				t.Errorf("%s: BackupPath = %s; want a file in %s", tt.name, result.BackupPath, backupDir)
+			}
+			data, err2 := os.ReadFile(result.BackupPath)
+			if tt.dryRun && !os.IsNotExist(err2) {
+				t.Errorf("%s: dry run created backup %s", tt.name, result.BackupPath)
+			} else if !tt.dryRun && string(data) != "[Security]\nPassphrase=existing\n" {
+				t.Errorf("%s: backup = %q, %v; want the existing profile", tt.name, data, err2)
+			}
+		}
+		entries, err := os.ReadDir(stateDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			if entry.Name() != "Home.psk" {
+				t.Errorf("%s: unexpected file %s left in the state directory", tt.name, entry.Name())
+			}
+		}
+	}
+}
+
+func TestImportKnownNetworksRejectsBackupDirectory(t *testing.T) {
+	stateDir := t.TempDir()
+	for _, backupDir := range []string{"", stateDir, filepath.Join(stateDir, "backups")} {
+		_, err := ImportKnownNetworks(stateDir, testKnownNetworkBundle(), ImportOptions{
+			Conflict:        ImportConflictRename,
+			BackupDirectory: backupDir,
+		})
+		if err == nil {
+			t.Errorf("ImportKnownNetworks accepted backup directory %q", backupDir)
+		}
+	}
+	if _, err := ImportKnownNetworks(stateDir, testKnownNetworkBundle(), ImportOptions{
+		Conflict:        ImportConflictRename,
+		BackupDirectory: stateDir + "-backups",
+	}); err != nil {
+		t.Errorf("ImportKnownNetworks rejected a sibling backup directory: %s", err)
+	}
+}
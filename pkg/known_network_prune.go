@@ -0,0 +1,94 @@
+package spider
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+type KnownNetworkPruneOption func(*knownNetworkPruneOptions)
+
+type knownNetworkPruneOptions struct {
+	olderThan      *time.Duration
+	neverConnected bool
+	except         []string
+	dryRun         bool
+}
+
+func OlderThan(age time.Duration) KnownNetworkPruneOption {
+	return func(o *knownNetworkPruneOptions) {
+		o.olderThan = &age
+	}
+}
+
+func NeverConnected() KnownNetworkPruneOption {
+	return func(o *knownNetworkPruneOptions) {
+		o.neverConnected = true
+	}
+}
+
+func Except(names ...string) KnownNetworkPruneOption {
+	return func(o *knownNetworkPruneOptions) {
+		o.except = append(o.except, names...)
+	}
+}
+
+func DryRun() KnownNetworkPruneOption {
+	return func(o *knownNetworkPruneOptions) {
+		o.dryRun = true
+	}
+}
+
+func (o *knownNetworkPruneOptions) matches(kn *KnownNetwork, now time.Time) bool {
+	if slices.Contains(o.except, kn.name) {
+		return false
+	}
+	if kn.lastConnectedTimeErr != nil {
+		log.Warnf("Not pruning KnownNetwork %s: %s", kn.name, kn.lastConnectedTimeErr)
+		return false
+	}
+	if kn.lastConnectedTime == nil {
+		return o.neverConnected
+	}
+	return o.olderThan != nil && now.Sub(*kn.lastConnectedTime) > *o.olderThan
+}
+
+func ForgetKnownNetworks(conn *dbus.Conn, options ...KnownNetworkPruneOption) ([]*KnownNetwork, error) {
+	o := &knownNetworkPruneOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	if o.olderThan == nil && !o.neverConnected {
+		log.Warn("No prune criteria given; not forgetting any KnownNetworks")
+		return nil, nil
+	}
+	knownNetworks, err := GetKnownNetworks(conn)
+	if err != nil {
+		log.Errorf("failed to get KnownNetworks to prune: %s", err)
+		return nil, err
+	}
+	now := time.Now()
+	pruned := make([]*KnownNetwork, 0)
+	var errs []error
+	for _, kn := range knownNetworks {
+		if !o.matches(kn, now) {
+			continue
+		}
+		if o.dryRun {
+			log.Infof("Would forget KnownNetwork %s", kn.name)
+			pruned = append(pruned, kn)
+			continue
+		}
+		if err = kn.Forget(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to forget KnownNetwork %s: %s", kn.name, err))
+			continue
+		}
+		log.Infof("Forgot KnownNetwork %s", kn.name)
+		pruned = append(pruned, kn)
+	}
+	return pruned, errors.Join(errs...)
+}
@@ -0,0 +1,115 @@
+package spider
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func exportTestKnownNetworks(t *testing.T, conn *dbus.Conn, lastConnectedTimes map[string]string) *[]string {
+	t.Helper()
+	var mu sync.Mutex
+	forgotten := make([]string, 0)
+	objects := make(testObjects, len(lastConnectedTimes))
+	for name, lastConnectedTime := range lastConnectedTimes {
+		path := dbus.ObjectPath("/net/connman/iwd/" + name + "_psk")
+		props := map[string]interface{}{
+			"Name":        name,
+			"Type":        NetworkTypePSK,
+			"Hidden":      false,
+			"AutoConnect": true,
+		}
+		if lastConnectedTime != "" {
+			props["LastConnectedTime"] = lastConnectedTime
+		}
+		objects[path] = map[string]map[string]interface{}{knownNetworkInterface: props}
+		if err := conn.ExportMethodTable(map[string]interface{}{
+			"Forget": func() *dbus.Error {
+				mu.Lock()
+				defer mu.Unlock()
+				forgotten = append(forgotten, name)
+				return nil
+			},
+		}, path, knownNetworkInterface); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exportTestObjects(t, conn, objects)
+	return &forgotten
+}
+
+func TestForgetKnownNetworks(t *testing.T) {
+	now := time.Now()
+	lastConnectedTimes := map[string]string{
+		"Recent":  now.Add(-time.Hour).Format(time.RFC3339),
+		"Old":     now.Add(-30 * 24 * time.Hour).Format(time.RFC3339),
+		"Cafe":    now.Add(-60 * 24 * time.Hour).Format(time.RFC3339),
+		"Never":   "",
+		"Garbled": "last tuesday",
+	}
+	tests := []struct {
+		name     string
+		options  []KnownNetworkPruneOption
+		expected []string
+		dryRun   bool
+	}{
+		{name: "no criteria", expected: []string{}},
+		{name: "older than", options: []KnownNetworkPruneOption{OlderThan(7 * 24 * time.Hour)}, expected: []string{"Cafe", "Old"}},
+		{name: "never connected", options: []KnownNetworkPruneOption{NeverConnected()}, expected: []string{"Never"}},
+		{
+			name:     "older than and never connected",
+			options:  []KnownNetworkPruneOption{OlderThan(7 * 24 * time.Hour), NeverConnected()},
+			expected: []string{"Cafe", "Never", "Old"},
+		},
+		{
+			name:     "except",
+			options:  []KnownNetworkPruneOption{OlderThan(7 * 24 * time.Hour), NeverConnected(), Except("Cafe", "Never")},
+			expected: []string{"Old"},
+		},
+		{
+			name:     "dry run",
+			options:  []KnownNetworkPruneOption{OlderThan(7 * 24 * time.Hour), DryRun()},
+			expected: []string{"Cafe", "Old"},
+			dryRun:   true,
+		},
+	}
+	for _, tt := range tests {
+		client, server := newTestConnPair(t)
+		forgotten := exportTestKnownNetworks(t, server, lastConnectedTimes)
+		pruned, err := ForgetKnownNetworks(client, tt.options...)
+		if err != nil {
+			t.Fatalf("%s: ForgetKnownNetworks failed: %s", tt.name, err)
+		}
+		names := make([]string, 0, len(pruned))
+		for _, kn := range pruned {
+			names = append(names, kn.name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, tt.expected) {
+			t.Errorf("%s: pruned %v; want %v", tt.name, names, tt.expected)
+		}
+		slices.Sort(*forgotten)
+		if tt.dryRun && len(*forgotten) != 0 {
+			t.Errorf("%s: dry run forgot %v", tt.name, *forgotten)
+		} else if !tt.dryRun && !slices.Equal(*forgotten, tt.expected) {
+			t.Errorf("%s: forgot %v; want %v", tt.name, *forgotten, tt.expected)
+		}
+	}
+}
+
+func TestForgetKnownNetworksSkipsUnparsableLastConnectedTime(t *testing.T) {
+	client, server := newTestConnPair(t)
+	forgotten := exportTestKnownNetworks(t, server, map[string]string{
+		"Garbled": "2024-13-45T99:00:00Z",
+	})
+	pruned, err := ForgetKnownNetworks(client, NeverConnected(), OlderThan(0))
+	if err != nil {
+		t.Fatalf("ForgetKnownNetworks failed: %s", err)
+	}
+	if len(pruned) != 0 || len(*forgotten) != 0 {
+		t.Errorf("network with an unparsable LastConnectedTime was pruned: %v", *forgotten)
+	}
+}
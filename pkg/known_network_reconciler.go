@@ -0,0 +1,201 @@
+package spider
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+type ReconcileActionType string
+
+const (
+	ReconcileActionAdd            ReconcileActionType = "add"
+	ReconcileActionRewrite        ReconcileActionType = "rewrite"
+	ReconcileActionSetAutoConnect ReconcileActionType = "set-autoconnect"
+	ReconcileActionForget         ReconcileActionType = "forget"
+)
+
+type ReconcileAction struct {
+	Type         ReconcileActionType
+	Name         string
+	NetType      string
+	Profile      *NetworkProfile
+	KnownNetwork *KnownNetwork
+	AutoConnect  bool
+}
+
+func (a *ReconcileAction) String() string {
+	switch a.Type {
+	case ReconcileActionSetAutoConnect:
+		return fmt.Sprintf("%s %s (%s) to %t", a.Type, a.Name, a.NetType, a.AutoConnect)
+	default:
+		return fmt.Sprintf("%s %s (%s)", a.Type, a.Name, a.NetType)
+	}
+}
+
+type ReconcilePlan struct {
+	Actions []*ReconcileAction
+}
+
+func (p *ReconcilePlan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+func (p *ReconcilePlan) String() string {
+	return fmt.Sprintf("%v", p.Actions)
+}
+
+type KnownNetworkReconciler struct {
+	conn     *dbus.Conn
+	stateDir string
+}
+
+func NewKnownNetworkReconciler(conn *dbus.Conn, stateDir string) *KnownNetworkReconciler {
+	if stateDir == "" {
+		stateDir = DefaultStateDirectory
+	}
+	return &KnownNetworkReconciler{
+		conn:     conn,
+		stateDir: stateDir,
+	}
+}
+
+func (r *KnownNetworkReconciler) Plan(desired []*NetworkProfile) (*ReconcilePlan, error) {
+	wanted := make(map[string]*NetworkProfile, len(desired))
+	for _, profile := range desired {
+		if err := profile.Validate(); err != nil {
+			log.Errorf("invalid desired profile %s: %s", profile, err)
+			return nil, err
+		}
+		key := profile.Type + "/" + profile.Name
+		if _, ok := wanted[key]; ok {
+			return nil, fmt.Errorf("%w: duplicate desired profile %s", ErrInvalidNetworkProfile, profile)
+		}
+		wanted[key] = profile
+	}
+	knownNetworks, err := GetKnownNetworks(r.conn)
+	if err != nil {
+		log.Errorf("failed to get KnownNetworks to reconcile: %s", err)
+		return nil, err
+	}
+	plan := &ReconcilePlan{
+		Actions: make([]*ReconcileAction, 0),
+	}
+	known := make(map[string]*KnownNetwork, len(knownNetworks))
+	for _, kn := range knownNetworks {
+		switch kn.netType {
+		case NetworkTypeOpen, NetworkTypePSK, NetworkType8021X:
+		default:
+			log.Debugf("Not reconciling KnownNetwork %s of type %s", kn.name, kn.netType)
+			continue
+		}
+		key := kn.netType + "/" + kn.name
+		known[key] = kn
+		if _, ok := wanted[key]; !ok {
+			plan.Actions = append(plan.Actions, &ReconcileAction{
+				Type:         ReconcileActionForget,
+				Name:         kn.name,
+				NetType:      kn.netType,
+				KnownNetwork: kn,
+			})
+		}
+	}
+	for _, profile := range desired {
+		kn, ok := known[profile.Type+"/"+profile.Name]
+		if !ok {
+			plan.Actions = append(plan.Actions, &ReconcileAction{
+				Type:    ReconcileActionAdd,
+				Name:    profile.Name,
+				NetType: profile.Type,
+				Profile: profile,
+			})
+			continue
+		}
+		existing, err2 := LoadKnownNetworkProfile(r.stateDir, kn)
+		if err2 != nil || !profilesEquivalent(profile, existing) {
+			rewrite := *profile
+			if existing != nil {
+				rewrite.doc = existing.doc
+			}
+			plan.Actions = append(plan.Actions, &ReconcileAction{
+				Type:         ReconcileActionRewrite,
+				Name:         profile.Name,
+				NetType:      profile.Type,
+				Profile:      &rewrite,
+				KnownNetwork: kn,
+			})
+		}
+		autoConnect := profile.Settings.AutoConnect == nil || *profile.Settings.AutoConnect
+		if kn.autoConnect != autoConnect {
+			plan.Actions = append(plan.Actions, &ReconcileAction{
+				Type:         ReconcileActionSetAutoConnect,
+				Name:         profile.Name,
+				NetType:      profile.Type,
+				KnownNetwork: kn,
+				AutoConnect:  autoConnect,
+			})
+		}
+	}
+	log.Debugf("Reconcile plan: %s", plan)
+	return plan, nil
+}
+
+func (r *KnownNetworkReconciler) Apply(plan *ReconcilePlan) error {
+	var errs []error
+	for _, action := range plan.Actions {
+		var err error
+		switch action.Type {
+		case ReconcileActionAdd, ReconcileActionRewrite:
+			_, err = WriteNetworkProfile(r.stateDir, action.Profile)
+		case ReconcileActionSetAutoConnect:
+			err = action.KnownNetwork.SetAutoConnect(action.AutoConnect)
+		case ReconcileActionForget:
+			err = action.KnownNetwork.Forget()
+			if errors.Is(err, ErrNetworkForgotten) {
+				err = nil
+			}
+		default:
+			err = fmt.Errorf("unknown reconcile action %s", action.Type)
+		}
+		if err != nil {
+			log.Errorf("failed to %s: %s", action, err)
+			errs = append(errs, fmt.Errorf("failed to %s: %s", action, err))
+			continue
+		}
+		log.Infof("Applied %s", action)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *KnownNetworkReconciler) Reconcile(desired []*NetworkProfile) (*ReconcilePlan, error) {
+	plan, err := r.Plan(desired)
+	if err != nil {
+		return nil, err
+	}
+	return plan, r.Apply(plan)
+}
+
+func profilesEquivalent(desired, existing *NetworkProfile) bool {
+	derived := desired.Security.Passphrase != nil
+	normalize := func(p *NetworkProfile) NetworkProfile {
+		n := *p
+		n.doc = nil
+		n.Settings.AutoConnect = nil
+		if derived {
+			n.Security.PreSharedKey = nil
+			n.Security.SAEPTGroup19 = nil
+			n.Security.SAEPTGroup20 = nil
+		}
+		if len(n.Security.EAP) == 0 {
+			n.Security.EAP = nil
+		}
+		if n.Settings.Hidden != nil && !*n.Settings.Hidden {
+			n.Settings.Hidden = nil
+		}
+		return n
+	}
+	return reflect.DeepEqual(normalize(desired), normalize(existing))
+}
@@ -0,0 +1,105 @@
+package spider
+
+import (
+	"testing"
+)
+
+func simulateIwdConnect(t *testing.T, stateDir string, kn *KnownNetwork) {
+	t.Helper()
+	profile, err := LoadKnownNetworkProfile(stateDir, kn)
+	if err != nil {
+		t.Fatalf("failed to load profile: %s", err)
+	}
+	if err = profile.DeriveCredentials(*profile.Security.Passphrase, true); err != nil {
+		t.Fatalf("failed to derive credentials: %s", err)
+	}
+	if _, err = WriteNetworkProfile(stateDir, profile); err != nil {
+		t.Fatalf("failed to write profile: %s", err)
+	}
+}
+
+func TestKnownNetworkReconcilerSettles(t *testing.T) {
+	client, server := newTestConnPair(t)
+	exportTestObjects(t, server, testObjects{
+		"/net/connman/iwd/486f6d65_psk": {
+			knownNetworkInterface: {
+				"Name":        "Home",
+				"Type":        NetworkTypePSK,
+				"Hidden":      false,
+				"AutoConnect": true,
+			},
+		},
+	})
+	stateDir := t.TempDir()
+	oldPassphrase := "oldpassword"
+	existing := NewNetworkProfile("Home", NetworkTypePSK)
+	existing.Security.Passphrase = &oldPassphrase
+	if _, err := WriteNetworkProfile(stateDir, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	passphrase := "newpassword"
+	desired := NewNetworkProfile("Home", NetworkTypePSK)
+	desired.Security.Passphrase = &passphrase
+	r := NewKnownNetworkReconciler(client, stateDir)
+
+	plan, err := r.Plan([]*NetworkProfile{desired})
+	if err != nil {
+		t.Fatalf("Plan failed: %s", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Type != ReconcileActionRewrite {
+		t.Fatalf("first plan = %s; want a single rewrite", plan)
+	}
+	if err = r.Apply(plan); err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+
+	simulateIwdConnect(t, stateDir, plan.Actions[0].KnownNetwork)
+	onDisk, err := LoadKnownNetworkProfile(stateDir, plan.Actions[0].KnownNetwork)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onDisk.Security.PreSharedKey == nil || onDisk.Security.SAEPTGroup19 == nil || onDisk.Security.SAEPTGroup20 == nil {
+		t.Fatalf("simulated connect did not write derived keys: %+v", onDisk.Security)
+	}
+
+	plan, err = r.Plan([]*NetworkProfile{desired})
+	if err != nil {
+		t.Fatalf("second Plan failed: %s", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("second plan = %s; want empty", plan)
+	}
+
+	changed := "changedpassword"
+	desired.Security.Passphrase = &changed
+	plan, err = r.Plan([]*NetworkProfile{desired})
+	if err != nil {
+		t.Fatalf("third Plan failed: %s", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Type != ReconcileActionRewrite {
+		t.Errorf("plan after passphrase change = %s; want a single rewrite", plan)
+	}
+}
+
+func TestProfilesEquivalentPreSharedKeyOnly(t *testing.T) {
+	psk, err := DerivePreSharedKey("Home", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := DerivePreSharedKey("Home", "otherpassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired := NewNetworkProfile("Home", NetworkTypePSK)
+	desired.Security.PreSharedKey = &psk
+	existing := NewNetworkProfile("Home", NetworkTypePSK)
+	existing.Security.PreSharedKey = &other
+	if profilesEquivalent(desired, existing) {
+		t.Errorf("profiles with different PreSharedKeys compared equivalent")
+	}
+	existing.Security.PreSharedKey = &psk
+	if !profilesEquivalent(desired, existing) {
+		t.Errorf("profiles with the same PreSharedKey compared different")
+	}
+}
@@ -1,7 +1,11 @@
 package spider
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/godbus/dbus/v5"
 	log "github.com/sirupsen/logrus"
@@ -32,6 +36,10 @@ type Networker interface {
 	GetKnownNetwork() *KnownNetwork
 	GetExtendedServiceSet() *[]BasicServiceSet
 	Connect() error
+	ConnectWithCredentials(ctx context.Context, credentials Credentials) error
+	Refresh() error
+	SetAutoRefresh(enabled bool) error
+	GetAutoRefresh() bool
 }
 
 type Network struct {
@@ -44,6 +52,9 @@ type Network struct {
 	netType            string
 	knownNetwork       *KnownNetwork
 	extendedServiceSet *[]BasicServiceSet
+	mu                 sync.RWMutex
+	stopAutoRefresh    func()
+	autoRefreshDone    chan struct{}
 }
 
 func NewNetwork(conn *dbus.Conn, path dbus.ObjectPath) (*Network, error) {
@@ -58,99 +69,110 @@ func NewNetwork(conn *dbus.Conn, path dbus.ObjectPath) (*Network, error) {
 		obj:  obj,
 		path: path,
 	}
+	if err := n.Refresh(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *Network) Refresh() error {
+	var name string
+	var connected bool
+	var device Device
+	var netType string
+	var knownNetwork *KnownNetwork
+	var extendedServiceSet *[]BasicServiceSet
 	if variant, err := n.obj.GetProperty(networkPropertyName); err != nil {
 		networkLogger.WithFields(log.Fields{
 			"err": err,
 		}).Error("Failed to get property 'name'")
-		return nil, err
+		return err
 	} else {
-		if err2 := variant.Store(&n.name); err2 != nil {
+		if err2 := variant.Store(&name); err2 != nil {
 			networkLogger.WithFields(log.Fields{
 				"err": err2,
 			}).Error("Failed to store property 'name'")
-			return nil, err2
+			return err2
 		}
-		networkLogger.Debugf("Name = %s", n.name)
+		networkLogger.Debugf("Name = %s", name)
 	}
 	if variant, err := n.obj.GetProperty(networkPropertyConnected); err != nil {
 		networkLogger.WithFields(log.Fields{
 			"err": err,
 		}).Error("Failed to get property 'connected'")
-		return nil, err
+		return err
 	} else {
-		if err2 := variant.Store(&n.connected); err2 != nil {
+		if err2 := variant.Store(&connected); err2 != nil {
 			networkLogger.WithFields(log.Fields{
 				"err": err2,
 			}).Error("Failed to store property 'connected'")
-			return nil, err2
+			return err2
 		}
-		networkLogger.Debugf("Connected = %t", n.connected)
+		networkLogger.Debugf("Connected = %t", connected)
 	}
 	if variant, err := n.obj.GetProperty(networkPropertyDevice); err != nil {
 		networkLogger.WithFields(log.Fields{
 			"err": err,
 		}).Error("Failed to get property 'device'")
-		return nil, err
+		return err
 	} else {
 		var path dbus.ObjectPath
 		if err2 := variant.Store(&path); err2 != nil {
 			networkLogger.WithFields(log.Fields{
 				"err": err2,
 			}).Error("Failed to store property 'device'")
-			return nil, err2
+			return err2
 		}
-		if device, err2 := NewDevice(n.conn, path); err2 != nil {
+		if dev, err2 := NewDevice(n.conn, path); err2 != nil {
 			networkLogger.WithFields(log.Fields{
 				"err": err2,
 			}).Error("Failed to create new Device from property 'device'")
-			return nil, err2
+			return err2
 		} else {
-			networkLogger.Debugf("Created new Device from property 'device': %s", device)
-			n.device = *device
+			networkLogger.Debugf("Created new Device from property 'device': %s", dev)
+			device = *dev
 		}
 	}
 	if variant, err := n.obj.GetProperty(networkPropertyType); err != nil {
 		networkLogger.WithFields(log.Fields{
 			"err": err,
 		}).Error("Failed to get property 'type'")
-		return nil, err
+		return err
 	} else {
-		if err2 := variant.Store(&n.netType); err2 != nil {
+		if err2 := variant.Store(&netType); err2 != nil {
 			networkLogger.WithFields(log.Fields{
 				"err": err2,
 			}).Error("Failed to store property 'type'")
-			return nil, err2
+			return err2
 		}
-		networkLogger.Debugf("Type = %s", n.netType)
+		networkLogger.Debugf("Type = %s", netType)
 	}
 	if variant, err := n.obj.GetProperty(networkPropertyKnownNetwork); err != nil {
 		networkLogger.WithFields(log.Fields{
 			"err": err,
 		}).Info("Failed to get optional property 'known network'")
-		n.knownNetwork = nil
 	} else {
 		var path dbus.ObjectPath
 		if err2 := variant.Store(&path); err2 != nil {
 			networkLogger.WithFields(log.Fields{
 				"err": err2,
 			}).Error("Failed to store property 'known network'")
-			return nil, err2
+			return err2
 		}
-		if knownNetwork, err2 := NewKnownNetwork(conn, path); err2 != nil {
+		if kn, err2 := NewKnownNetwork(n.conn, path); err2 != nil {
 			networkLogger.WithFields(log.Fields{
 				"err": err2,
 			}).Error("Failed to create new Known Network from property 'known network'")
-			return nil, err2
+			return err2
 		} else {
-			networkLogger.Debugf("Created new Known Network from property 'known network': %s", knownNetwork)
-			n.knownNetwork = knownNetwork
+			networkLogger.Debugf("Created new Known Network from property 'known network': %s", kn)
+			knownNetwork = kn
 		}
 	}
 	if variant, err := n.obj.GetProperty(networkPropertyExtendedServiceSet); err != nil {
 		networkLogger.WithFields(log.Fields{
 			"err": err,
 		}).Info("Failed to get optional property 'extended service set'")
-		n.extendedServiceSet = nil
 	} else {
 		var paths []dbus.ObjectPath
 		var ess = make([]BasicServiceSet, 0)
@@ -158,22 +180,203 @@ func NewNetwork(conn *dbus.Conn, path dbus.ObjectPath) (*Network, error) {
 			networkLogger.WithFields(log.Fields{
 				"err": err2,
 			}).Error("Failed to store property 'extended service set'")
-			return nil, err2
+			return err2
 		}
 		for i, path := range paths {
 			if bss, err2 := NewBasicServiceSet(n.conn, path); err2 != nil {
 				networkLogger.WithFields(log.Fields{
 					"err": err2,
 				}).Error("Failed to create new Basic Service Set from property 'extended service set'")
-				return nil, err2
+				return err2
 			} else {
 				networkLogger.Debugf("Created new Basic Service Set %d from property 'extended service set': %s", i, bss)
 				ess = append(ess, *bss)
 			}
 		}
+		n.enrichExtendedServiceSet(device.GetPath(), ess)
+		extendedServiceSet = &ess
+	}
+	n.mu.Lock()
+	n.name = name
+	n.connected = connected
+	n.device = device
+	n.netType = netType
+	n.knownNetwork = knownNetwork
+	n.extendedServiceSet = extendedServiceSet
+	n.mu.Unlock()
+	return nil
+}
+
+func (n *Network) SetAutoRefresh(enabled bool) error {
+	n.mu.Lock()
+	if !enabled {
+		stop, done := n.stopAutoRefresh, n.autoRefreshDone
+		n.stopAutoRefresh = nil
+		n.autoRefreshDone = nil
+		n.mu.Unlock()
+		if stop != nil {
+			stop()
+			<-done
+			networkLogger.Debug("Disabled auto refresh")
+		}
+		return nil
+	}
+	defer n.mu.Unlock()
+	if n.stopAutoRefresh != nil {
+		return nil
+	}
+	changes, stop, err := watchPropertiesChanged(n.conn, n.path, networkInterface)
+	if err != nil {
+		networkLogger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to enable auto refresh")
+		return err
+	}
+	done := make(chan struct{})
+	n.stopAutoRefresh = stop
+	n.autoRefreshDone = done
+	go func() {
+		defer close(done)
+		for change := range changes {
+			if err2 := n.applyPropertiesChange(change); err2 != nil {
+				networkLogger.WithFields(log.Fields{
+					"err": err2,
+				}).Error("Failed to refresh after properties changed")
+			}
+		}
+	}()
+	networkLogger.Debug("Enabled auto refresh")
+	return nil
+}
+
+func (n *Network) applyPropertiesChange(change *propertiesChange) error {
+	for name, variant := range change.changed {
+		if err := n.updateProperty(name, variant); err != nil {
+			return err
+		}
+	}
+	for _, name := range change.invalidated {
+		variant, err := n.obj.GetProperty(networkInterface + "." + name)
+		if err != nil {
+			networkLogger.WithFields(log.Fields{
+				"err": err,
+			}).Infof("Property '%s' was invalidated and is no longer available", name)
+			n.clearProperty(name)
+			continue
+		}
+		if err = n.updateProperty(name, variant); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Network) updateProperty(name string, variant dbus.Variant) error {
+	switch name {
+	case "Name":
+		var value string
+		if err := variant.Store(&value); err != nil {
+			return fmt.Errorf("failed to store changed property 'name': %s", err)
+		}
+		n.mu.Lock()
+		n.name = value
+		n.mu.Unlock()
+	case "Connected":
+		var value bool
+		if err := variant.Store(&value); err != nil {
+			return fmt.Errorf("failed to store changed property 'connected': %s", err)
+		}
+		n.mu.Lock()
+		n.connected = value
+		n.mu.Unlock()
+	case "Type":
+		var value string
+		if err := variant.Store(&value); err != nil {
+			return fmt.Errorf("failed to store changed property 'type': %s", err)
+		}
+		n.mu.Lock()
+		n.netType = value
+		n.mu.Unlock()
+	case "Device":
+		var path dbus.ObjectPath
+		if err := variant.Store(&path); err != nil {
+			return fmt.Errorf("failed to store changed property 'device': %s", err)
+		}
+		if current := n.GetDevice(); current.GetPath() == path {
+			return nil
+		}
+		dev, err := NewDevice(n.conn, path)
+		if err != nil {
+			return err
+		}
+		n.mu.Lock()
+		n.device = *dev
+		n.mu.Unlock()
+	case "KnownNetwork":
+		var path dbus.ObjectPath
+		if err := variant.Store(&path); err != nil {
+			return fmt.Errorf("failed to store changed property 'known network': %s", err)
+		}
+		if kn := n.GetKnownNetwork(); kn != nil && kn.GetPath() == path {
+			return nil
+		}
+		kn, err := NewKnownNetwork(n.conn, path)
+		if err != nil {
+			return err
+		}
+		n.mu.Lock()
+		n.knownNetwork = kn
+		n.mu.Unlock()
+	case "ExtendedServiceSet":
+		var paths []dbus.ObjectPath
+		if err := variant.Store(&paths); err != nil {
+			return fmt.Errorf("failed to store changed property 'extended service set': %s", err)
+		}
+		existing := make(map[dbus.ObjectPath]BasicServiceSet)
+		if current := n.GetExtendedServiceSet(); current != nil {
+			for _, bss := range *current {
+				existing[bss.path] = bss
+			}
+		}
+		ess := make([]BasicServiceSet, 0, len(paths))
+		for _, path := range paths {
+			if bss, ok := existing[path]; ok {
+				ess = append(ess, bss)
+				continue
+			}
+			bss, err := NewBasicServiceSet(n.conn, path)
+			if err != nil {
+				return err
+			}
+			ess = append(ess, *bss)
+		}
+		device := n.GetDevice()
+		n.enrichExtendedServiceSet(device.GetPath(), ess)
+		n.mu.Lock()
 		n.extendedServiceSet = &ess
+		n.mu.Unlock()
+	default:
+		return nil
 	}
-	return n, nil
+	networkLogger.Debugf("Updated changed property '%s'", name)
+	return nil
+}
+
+func (n *Network) clearProperty(name string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch name {
+	case "KnownNetwork":
+		n.knownNetwork = nil
+	case "ExtendedServiceSet":
+		n.extendedServiceSet = nil
+	}
+}
+
+func (n *Network) GetAutoRefresh() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.stopAutoRefresh != nil
 }
 
 func (n *Network) GetPath() dbus.ObjectPath {
@@ -185,26 +388,123 @@ func (n *Network) GetInterface() string {
 }
 
 func (n *Network) GetName() (string, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	return n.name, nil
 }
 
 func (n *Network) GetConnected() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	return n.connected
 }
 
 func (n *Network) GetDevice() Device {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	return n.device
 }
 
 func (n *Network) GetType() (string, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	return n.netType, nil
 }
 
 func (n *Network) GetKnownNetwork() *KnownNetwork {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.knownNetwork != nil && n.knownNetwork.forgotten.Load() {
+		return nil
+	}
 	return n.knownNetwork
 }
 
+func (n *Network) GetExtendedServiceSet() *[]BasicServiceSet {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.extendedServiceSet
+}
+
+func (n *Network) enrichExtendedServiceSet(station dbus.ObjectPath, ess []BasicServiceSet) {
+	stationObj := n.conn.Object(IwdService, station)
+	var connectedPath dbus.ObjectPath
+	if variant, err := stationObj.GetProperty(stationPropertyConnectedAccessPoint); err != nil {
+		networkLogger.WithFields(log.Fields{
+			"err": err,
+		}).Info("Failed to get optional Station property 'connected access point'")
+	} else if err2 := variant.Store(&connectedPath); err2 != nil {
+		networkLogger.WithFields(log.Fields{
+			"err": err2,
+		}).Error("Failed to store Station property 'connected access point'")
+	}
+	var networks map[dbus.ObjectPath][]map[string]dbus.Variant
+	if !n.hasStationDebug(station) {
+		networkLogger.Debug("StationDebug is not available; only the connected Basic Service Set will be enriched")
+	} else if err := stationObj.Call(stationDebugMethodGetNetworks, 0).Store(&networks); err != nil {
+		networkLogger.WithFields(log.Fields{
+			"err": err,
+		}).Info("Failed to get optional debug scan data for Extended Service Set")
+	}
+	scanned := make(map[string]map[string]dbus.Variant)
+	for _, info := range networks[n.path] {
+		var address string
+		if variant, ok := info["Address"]; ok && variant.Store(&address) == nil {
+			scanned[strings.ToUpper(address)] = info
+		}
+	}
+	var diagnostics map[string]dbus.Variant
+	if connectedPath != "" {
+		if err := stationObj.Call(stationDiagnosticMethodGetDiagnostics, 0).Store(&diagnostics); err != nil {
+			networkLogger.WithFields(log.Fields{
+				"err": err,
+			}).Info("Failed to get optional diagnostics for connected Basic Service Set")
+		}
+	}
+	for i := range ess {
+		bss := &ess[i]
+		bss.connected = connectedPath != "" && bss.path == connectedPath
+		if info, ok := scanned[strings.ToUpper(bss.address)]; ok {
+			var frequency uint32
+			var signalStrength int16
+			if variant, ok2 := info["Frequency"]; ok2 && variant.Store(&frequency) == nil {
+				bss.frequency = &frequency
+			}
+			if variant, ok2 := info["RSSI"]; ok2 && variant.Store(&signalStrength) == nil {
+				bss.signalStrength = &signalStrength
+			}
+		}
+		if bss.connected && diagnostics != nil {
+			var frequency uint32
+			var rssi int16
+			if variant, ok := diagnostics["Frequency"]; ok && variant.Store(&frequency) == nil {
+				bss.frequency = &frequency
+			}
+			if variant, ok := diagnostics["RSSI"]; ok && variant.Store(&rssi) == nil {
+				signalStrength := rssi * 100
+				bss.signalStrength = &signalStrength
+			}
+		}
+		networkLogger.Debugf("Enriched Basic Service Set %d: %s", i, bss)
+	}
+}
+
+func (n *Network) hasStationDebug(station dbus.ObjectPath) bool {
+	var objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	objectManager := n.conn.Object(IwdService, "/")
+	if err := objectManager.Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&objects); err != nil {
+		networkLogger.WithFields(log.Fields{
+			"err": err,
+		}).Info("Failed to get managed objects")
+		return false
+	}
+	_, ok := objects[station][stationDebugInterface]
+	return ok
+}
+
 func (n *Network) String() string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	var kn string
 	var ess string
 	if n.knownNetwork == nil {
@@ -228,6 +528,47 @@ func (n *Network) Connect() error {
 		return err
 	}
 	networkLogger.Debugf("Connected")
+	n.mu.Lock()
 	n.connected = true
+	n.mu.Unlock()
 	return nil
 }
+
+func (n *Network) ConnectWithCredentials(ctx context.Context, credentials Credentials) error {
+	server, err := ServeAgent(ctx, n.conn, newScopedAgent(n.conn, n.path, credentials))
+	if err != nil {
+		networkLogger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to serve agent for Connect with credentials")
+		return err
+	}
+	defer func() {
+		_ = server.Close()
+	}()
+	if err = n.obj.CallWithContext(ctx, networkMethodConnect, 0).Err; err != nil {
+		networkLogger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to call Connect with credentials")
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return errors.Join(ctxErr, err)
+		}
+		return err
+	}
+	networkLogger.Debugf("Connected with credentials")
+	n.mu.Lock()
+	n.connected = true
+	n.mu.Unlock()
+	return nil
+}
+
+func getNetworkNameAndType(conn *dbus.Conn, path dbus.ObjectPath) (string, string, error) {
+	obj := conn.Object(IwdService, path)
+	var name, netType string
+	if err := obj.StoreProperty(networkPropertyName, &name); err != nil {
+		return "", "", fmt.Errorf("failed to get Name of Network %s: %s", path, err)
+	}
+	if err := obj.StoreProperty(networkPropertyType, &netType); err != nil {
+		return "", "", fmt.Errorf("failed to get Type of Network %s: %s", path, err)
+	}
+	return name, netType, nil
+}
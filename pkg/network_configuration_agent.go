@@ -1,7 +1,10 @@
 package spider
 
 import (
+	"fmt"
+
 	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -44,8 +47,152 @@ type NetworkConfigurationAgentClient interface {
 	GetConnection() *dbus.Conn
 	GetPath() dbus.ObjectPath
 	Release() *dbus.Error
-	//ConfigureIPv4(device dbus.ObjectPath, config map[string]dbus.Variant) *dbus.Error
-	//ConfigureIPv6(device dbus.ObjectPath, config map[string]dbus.Variant) *dbus.Error
+	ConfigureIPv4(device dbus.ObjectPath, config map[string]dbus.Variant) *dbus.Error
+	ConfigureIPv6(device dbus.ObjectPath, config map[string]dbus.Variant) *dbus.Error
 	CancelIPv4(device dbus.ObjectPath, reason string) *dbus.Error
 	CancelIPv6(device dbus.ObjectPath, reason string) *dbus.Error
 }
+
+func DecodeNetworkConfigurationConfig(config map[string]dbus.Variant) (*NetworkConfigurationConfig, error) {
+	c := &NetworkConfigurationConfig{}
+	if _, ok := config["Method"]; !ok {
+		return nil, fmt.Errorf("network configuration is missing Method")
+	}
+	for key, variant := range config {
+		var err error
+		switch key {
+		case "Method":
+			err = decodeVariant(key, variant, &c.Method)
+		case "Addresses":
+			var addresses []map[string]dbus.Variant
+			if addresses, err = decodeDictArrayVariant(key, variant); err != nil {
+				break
+			}
+			c.Addresses = make([]NetworkConfigurationAddress, 0, len(addresses))
+			for i, address := range addresses {
+				a, err2 := decodeNetworkConfigurationAddress(address)
+				if err2 != nil {
+					return nil, fmt.Errorf("Addresses[%d]: %s", i, err2)
+				}
+				c.Addresses = append(c.Addresses, *a)
+			}
+		case "Routes":
+			var routes []map[string]dbus.Variant
+			if routes, err = decodeDictArrayVariant(key, variant); err != nil {
+				break
+			}
+			c.Routes = make([]NetworkConfigurationRoute, 0, len(routes))
+			for i, route := range routes {
+				r, err2 := decodeNetworkConfigurationRoute(route)
+				if err2 != nil {
+					return nil, fmt.Errorf("Routes[%d]: %s", i, err2)
+				}
+				c.Routes = append(c.Routes, *r)
+			}
+		case "DomainNameServers":
+			c.DomainNameServers = new([]string)
+			err = decodeVariant(key, variant, c.DomainNameServers)
+		case "DomainNames":
+			c.DomainNames = new([]string)
+			err = decodeVariant(key, variant, c.DomainNames)
+		case "MDNS":
+			c.MDNS = new(string)
+			err = decodeVariant(key, variant, c.MDNS)
+		default:
+			log.Debugf("Ignoring unknown network configuration key %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func decodeNetworkConfigurationAddress(address map[string]dbus.Variant) (*NetworkConfigurationAddress, error) {
+	a := &NetworkConfigurationAddress{}
+	if _, ok := address["Address"]; !ok {
+		return nil, fmt.Errorf("address is missing Address")
+	}
+	for key, variant := range address {
+		var err error
+		switch key {
+		case "Address":
+			err = decodeVariant(key, variant, &a.Address)
+		case "PrefixLength":
+			a.PrefixLength = new(byte)
+			err = decodeVariant(key, variant, a.PrefixLength)
+		case "Broadcast":
+			a.Broadcast = new(string)
+			err = decodeVariant(key, variant, a.Broadcast)
+		case "ValidLifetime":
+			a.ValidLifetime = new(uint32)
+			err = decodeVariant(key, variant, a.ValidLifetime)
+		case "PreferredLifetime":
+			a.PreferredLifetime = new(uint32)
+			err = decodeVariant(key, variant, a.PreferredLifetime)
+		default:
+			log.Debugf("Ignoring unknown network configuration address key %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func decodeNetworkConfigurationRoute(route map[string]dbus.Variant) (*NetworkConfigurationRoute, error) {
+	r := &NetworkConfigurationRoute{}
+	for key, variant := range route {
+		var err error
+		switch key {
+		case "Destination":
+			r.Destination = &NetworkConfigurationRouteDestination{}
+			err = decodeVariant(key, variant, r.Destination)
+		case "Router":
+			r.Router = new(string)
+			err = decodeVariant(key, variant, r.Router)
+		case "PreferredSource":
+			r.PreferredSource = new(string)
+			err = decodeVariant(key, variant, r.PreferredSource)
+		case "Lifetime":
+			r.Lifetime = new(uint32)
+			err = decodeVariant(key, variant, r.Lifetime)
+		case "Priority":
+			err = decodeVariant(key, variant, &r.Priority)
+		case "Preference":
+			r.Preference = new(byte)
+			err = decodeVariant(key, variant, r.Preference)
+		case "MTU":
+			r.MTU = new(uint32)
+			err = decodeVariant(key, variant, r.MTU)
+		default:
+			log.Debugf("Ignoring unknown network configuration route key %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func decodeVariant(key string, variant dbus.Variant, value interface{}) error {
+	expected := dbus.SignatureOf(value).String()
+	if actual := variant.Signature().String(); actual != expected {
+		return fmt.Errorf("%s must be of type %s; got %s", key, expected, actual)
+	}
+	if err := variant.Store(value); err != nil {
+		return fmt.Errorf("failed to decode %s: %s", key, err)
+	}
+	return nil
+}
+
+func decodeDictArrayVariant(key string, variant dbus.Variant) ([]map[string]dbus.Variant, error) {
+	if actual := variant.Signature().String(); actual != "aa{sv}" {
+		return nil, fmt.Errorf("%s must be of type aa{sv}; got %s", key, actual)
+	}
+	dicts, ok := variant.Value().([]map[string]dbus.Variant)
+	if !ok {
+		return nil, fmt.Errorf("%s must be of type aa{sv}", key)
+	}
+	return dicts, nil
+}
@@ -0,0 +1,180 @@
+package spider
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func testIPv4Configuration() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"Method": dbus.MakeVariant("static"),
+		"Addresses": dbus.MakeVariant([]map[string]dbus.Variant{
+			{
+				"Address":       dbus.MakeVariant("192.168.1.10"),
+				"PrefixLength":  dbus.MakeVariant(byte(24)),
+				"Broadcast":     dbus.MakeVariant("192.168.1.255"),
+				"ValidLifetime": dbus.MakeVariant(uint32(3600)),
+				"Unknown":       dbus.MakeVariant(true),
+			},
+		}),
+		"Routes": dbus.MakeVariant([]map[string]dbus.Variant{
+			{
+				"Destination": dbus.MakeVariant(NetworkConfigurationRouteDestination{IP: "0.0.0.0", Length: 0}),
+				"Router":      dbus.MakeVariant("192.168.1.1"),
+				"Priority":    dbus.MakeVariant(uint32(100)),
+				"MTU":         dbus.MakeVariant(uint32(1500)),
+			},
+		}),
+		"DomainNameServers": dbus.MakeVariant([]string{"192.168.1.1", "9.9.9.9"}),
+		"DomainNames":       dbus.MakeVariant([]string{"home.lan"}),
+		"MDNS":              dbus.MakeVariant("resolve"),
+		"Unknown":           dbus.MakeVariant(uint32(1)),
+	}
+}
+
+func TestDecodeNetworkConfigurationConfig(t *testing.T) {
+	c, err := DecodeNetworkConfigurationConfig(testIPv4Configuration())
+	if err != nil {
+		t.Fatalf("DecodeNetworkConfigurationConfig failed: %s", err)
+	}
+	prefixLength := byte(24)
+	broadcast := "192.168.1.255"
+	validLifetime := uint32(3600)
+	router := "192.168.1.1"
+	mtu := uint32(1500)
+	mdns := "resolve"
+	expected := &NetworkConfigurationConfig{
+		Method: "static",
+		Addresses: []NetworkConfigurationAddress{
+			{Address: "192.168.1.10", PrefixLength: &prefixLength, Broadcast: &broadcast, ValidLifetime: &validLifetime},
+		},
+		Routes: []NetworkConfigurationRoute{
+			{Destination: &NetworkConfigurationRouteDestination{IP: "0.0.0.0"}, Router: &router, Priority: 100, MTU: &mtu},
+		},
+		DomainNameServers: &[]string{"192.168.1.1", "9.9.9.9"},
+		DomainNames:       &[]string{"home.lan"},
+		MDNS:              &mdns,
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("DecodeNetworkConfigurationConfig = %+v; want %+v", c, expected)
+	}
+
+	c, err = DecodeNetworkConfigurationConfig(map[string]dbus.Variant{"Method": dbus.MakeVariant("dhcp")})
+	if err != nil {
+		t.Fatalf("DecodeNetworkConfigurationConfig with only Method failed: %s", err)
+	}
+	if c.Method != "dhcp" || c.Addresses != nil || c.DomainNameServers != nil || c.DomainNames != nil || c.MDNS != nil {
+		t.Errorf("DecodeNetworkConfigurationConfig with only Method = %+v", c)
+	}
+}
+
+func TestDecodeNetworkConfigurationConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		value  interface{}
+		delete bool
+		err    string
+	}{
+		{name: "missing Method", key: "Method", delete: true, err: "network configuration is missing Method"},
+		{name: "Method type", key: "Method", value: uint32(1), err: "Method must be of type s; got u"},
+		{name: "Addresses type", key: "Addresses", value: []string{"192.168.1.10"}, err: "Addresses must be of type aa{sv}; got as"},
+		{name: "Addresses dict type", key: "Addresses", value: []map[string]string{{"Address": "192.168.1.10"}}, err: "Addresses must be of type aa{sv}; got aa{ss}"},
+		{
+			name:  "missing Address",
+			key:   "Addresses",
+			value: []map[string]dbus.Variant{{"PrefixLength": dbus.MakeVariant(byte(24))}},
+			err:   "Addresses[0]: address is missing Address",
+		},
+		{
+			name:  "Address type",
+			key:   "Addresses",
+			value: []map[string]dbus.Variant{{"Address": dbus.MakeVariant([]byte{192, 168, 1, 10})}},
+			err:   "Addresses[0]: Address must be of type s; got ay",
+		},
+		{
+			name: "PrefixLength type",
+			key:  "Addresses",
+			value: []map[string]dbus.Variant{
+				{"Address": dbus.MakeVariant("192.168.1.10")},
+				{"Address": dbus.MakeVariant("192.168.1.11"), "PrefixLength": dbus.MakeVariant(uint32(24))},
+			},
+			err: "Addresses[1]: PrefixLength must be of type y; got u",
+		},
+		{
+			name:  "Router type",
+			key:   "Routes",
+			value: []map[string]dbus.Variant{{"Router": dbus.MakeVariant(dbus.ObjectPath("/gateway"))}},
+			err:   "Routes[0]: Router must be of type s; got o",
+		},
+		{
+			name:  "Destination type",
+			key:   "Routes",
+			value: []map[string]dbus.Variant{{"Destination": dbus.MakeVariant("0.0.0.0/0")}},
+			err:   "Routes[0]: Destination must be of type (sy); got s",
+		},
+		{
+			name:  "Priority type",
+			key:   "Routes",
+			value: []map[string]dbus.Variant{{"Priority": dbus.MakeVariant(int32(100))}},
+			err:   "Routes[0]: Priority must be of type u; got i",
+		},
+		{name: "DomainNameServers type", key: "DomainNameServers", value: "192.168.1.1", err: "DomainNameServers must be of type as; got s"},
+		{name: "DomainNameServers element type", key: "DomainNameServers", value: [][]byte{{192, 168, 1, 1}}, err: "DomainNameServers must be of type as; got aay"},
+		{name: "DomainNames type", key: "DomainNames", value: "home.lan", err: "DomainNames must be of type as; got s"},
+		{name: "MDNS type", key: "MDNS", value: true, err: "MDNS must be of type s; got b"},
+	}
+	for _, tt := range tests {
+		config := testIPv4Configuration()
+		if tt.delete {
+			delete(config, tt.key)
+		} else {
+			config[tt.key] = dbus.MakeVariant(tt.value)
+		}
+		c, err := DecodeNetworkConfigurationConfig(config)
+		if err == nil {
+			t.Errorf("%s: DecodeNetworkConfigurationConfig = %+v; want error %q", tt.name, c, tt.err)
+		} else if err.Error() != tt.err {
+			t.Errorf("%s: DecodeNetworkConfigurationConfig error = %q; want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestBaseNetworkConfigurationAgentConfigure(t *testing.T) {
+	var configured *NetworkConfigurationConfig
+	var configuredDevice dbus.ObjectPath
+	failure := errors.New("no such interface")
+	agent := NewBaseNetworkConfigurationAgent(nil, NetworkConfigurationCallbacks{
+		ConfigureIPv4: func(device dbus.ObjectPath, config *NetworkConfigurationConfig) error {
+			configuredDevice = device
+			configured = config
+			return nil
+		},
+		ConfigureIPv6: func(device dbus.ObjectPath, config *NetworkConfigurationConfig) error {
+			return failure
+		},
+	})
+	if err := agent.ConfigureIPv4(testDevicePath, testIPv4Configuration()); err != nil {
+		t.Fatalf("ConfigureIPv4 failed: %s", err)
+	}
+	if configuredDevice != testDevicePath || configured == nil || configured.Method != "static" || len(configured.Addresses) != 1 {
+		t.Errorf("ConfigureIPv4 callback got %s, %+v", configuredDevice, configured)
+	}
+
+	configured = nil
+	err := agent.ConfigureIPv4(testDevicePath, map[string]dbus.Variant{"Method": dbus.MakeVariant(uint32(1))})
+	if err == nil || err.Name != dbusErrorInvalidArgs || len(err.Body) != 1 || err.Body[0] != "Method must be of type s; got u" {
+		t.Errorf("ConfigureIPv4 with a wrongly typed Method error = %v; want %s", err, dbusErrorInvalidArgs)
+	}
+	if configured != nil {
+		t.Errorf("ConfigureIPv4 callback ran for an invalid configuration")
+	}
+
+	err = agent.ConfigureIPv6(testDevicePath, map[string]dbus.Variant{"Method": dbus.MakeVariant("auto")})
+	if err == nil || err.Name != "org.freedesktop.DBus.Error.Failed" || len(err.Body) != 1 || err.Body[0] != failure.Error() {
+		t.Errorf("ConfigureIPv6 with a failing callback error = %v; want org.freedesktop.DBus.Error.Failed", err)
+	}
+}
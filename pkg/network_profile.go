@@ -0,0 +1,327 @@
+package spider
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultStateDirectory = "/var/lib/iwd"
+	NetworkTypeOpen       = "open"
+	NetworkTypePSK        = "psk"
+	NetworkType8021X      = "8021x"
+	networkProfileMode    = 0600
+	networkProfileDirMode = 0700
+	maxSSIDLength         = 32
+
+	profileSectionSecurity = "Security"
+	profileSectionSettings = "Settings"
+	profileSectionNetwork  = "Network"
+	profileSectionIPv4     = "IPv4"
+	profileSectionIPv6     = "IPv6"
+)
+
+var (
+	ErrInvalidNetworkProfile = errors.New("invalid network profile")
+)
+
+type NetworkProfileSecurity struct {
+	Passphrase   *string
+	PreSharedKey *string
+	SAEPTGroup19 *string
+	SAEPTGroup20 *string
+	EAP          map[string]string
+}
+
+type NetworkProfileSettings struct {
+	AutoConnect            *bool
+	Hidden                 *bool
+	AlwaysRandomizeAddress *bool
+	AddressOverride        *string
+}
+
+type NetworkProfileNetwork struct {
+	EnableIPv6           *bool
+	NameResolvingService *string
+}
+
+type NetworkProfileIPv4 struct {
+	Address    string
+	Netmask    *string
+	Gateway    *string
+	Broadcast  *string
+	DNS        []string
+	DomainName *string
+}
+
+type NetworkProfileIPv6 struct {
+	Address string
+	Gateway *string
+	DNS     []string
+}
+
+type NetworkProfile struct {
+	Name     string
+	Type     string
+	Security NetworkProfileSecurity
+	Settings NetworkProfileSettings
+	Network  NetworkProfileNetwork
+	IPv4     *NetworkProfileIPv4
+	IPv6     *NetworkProfileIPv6
+	doc      *iniDocument
+}
+
+func NewNetworkProfile(name, netType string) *NetworkProfile {
+	return &NetworkProfile{
+		Name: name,
+		Type: netType,
+	}
+}
+
+func (p *NetworkProfile) String() string {
+	return fmt.Sprintf("{Name: %s, Type: %s}", p.Name, p.Type)
+}
+
+func (p *NetworkProfile) FileName() (string, error) {
+	return ProfileFileName(p.Name, p.Type)
+}
+
+func (p *NetworkProfile) DeriveCredentials(passphrase string, keepPassphrase bool) error {
+	if p.Type != NetworkTypePSK {
+		return fmt.Errorf("%w: cannot derive credentials for %s network %s", ErrInvalidNetworkProfile, p.Type, p.Name)
+	}
+	psk, err := DerivePreSharedKey(p.Name, passphrase)
+	if err != nil {
+		log.Errorf("failed to derive PreSharedKey for network profile %s: %s", p.Name, err)
+		return err
+	}
+	pt19, err := DeriveSAEPT(19, p.Name, passphrase)
+	if err != nil {
+		log.Errorf("failed to derive SAE-PT-Group19 for network profile %s: %s", p.Name, err)
+		return err
+	}
+	pt20, err := DeriveSAEPT(20, p.Name, passphrase)
+	if err != nil {
+		log.Errorf("failed to derive SAE-PT-Group20 for network profile %s: %s", p.Name, err)
+		return err
+	}
+	if keepPassphrase {
+		p.Security.Passphrase = &passphrase
+	} else {
+		p.Security.Passphrase = nil
+	}
+	p.Security.PreSharedKey = &psk
+	p.Security.SAEPTGroup19 = &pt19
+	p.Security.SAEPTGroup20 = &pt20
+	log.Debugf("Derived credentials for network profile %s", p.Name)
+	return nil
+}
+
+func (p *NetworkProfile) Validate() error {
+	if len(p.Name) == 0 || len(p.Name) > maxSSIDLength {
+		return fmt.Errorf("%w: SSID must be between 1 and 32 bytes long", ErrInvalidNetworkProfile)
+	}
+	switch p.Type {
+	case NetworkTypeOpen:
+		if p.Security.Passphrase != nil || p.Security.PreSharedKey != nil || p.Security.SAEPTGroup19 != nil || p.Security.SAEPTGroup20 != nil || len(p.Security.EAP) > 0 {
+			return fmt.Errorf("%w: open network %s must not have security settings", ErrInvalidNetworkProfile, p.Name)
+		}
+	case NetworkTypePSK:
+		if p.Security.Passphrase == nil && p.Security.PreSharedKey == nil {
+			return fmt.Errorf("%w: psk network %s requires a Passphrase or PreSharedKey", ErrInvalidNetworkProfile, p.Name)
+		}
+		if p.Security.Passphrase != nil {
+			if err := validatePassphrase(*p.Security.Passphrase); err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidNetworkProfile, err)
+			}
+		}
+		if p.Security.PreSharedKey != nil {
+			if err := validatePreSharedKey(*p.Security.PreSharedKey); err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidNetworkProfile, err)
+			}
+		}
+		if p.Security.SAEPTGroup19 != nil {
+			if err := validateSAEPT(19, *p.Security.SAEPTGroup19); err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidNetworkProfile, err)
+			}
+		}
+		if p.Security.SAEPTGroup20 != nil {
+			if err := validateSAEPT(20, *p.Security.SAEPTGroup20); err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidNetworkProfile, err)
+			}
+		}
+	case NetworkType8021X:
+		if _, ok := p.Security.EAP["EAP-Method"]; !ok {
+			return fmt.Errorf("%w: 8021x network %s requires an EAP-Method", ErrInvalidNetworkProfile, p.Name)
+		}
+		for key, value := range p.Security.EAP {
+			if err := validateEAPSetting(key, value); err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidNetworkProfile, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unknown network type %s", ErrInvalidNetworkProfile, p.Type)
+	}
+	if p.IPv4 != nil && p.IPv4.Address == "" {
+		return fmt.Errorf("%w: static IPv4 configuration requires an Address", ErrInvalidNetworkProfile)
+	}
+	if p.IPv6 != nil && p.IPv6.Address == "" {
+		return fmt.Errorf("%w: static IPv6 configuration requires an Address", ErrInvalidNetworkProfile)
+	}
+	return nil
+}
+
+func (p *NetworkProfile) Marshal() ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if p.doc == nil {
+		p.doc = newIniDocument()
+	}
+	d := p.doc
+
+	setOptionalString(d, profileSectionSecurity, "Passphrase", p.Security.Passphrase)
+	setOptionalString(d, profileSectionSecurity, "PreSharedKey", p.Security.PreSharedKey)
+	setOptionalString(d, profileSectionSecurity, "SAE-PT-Group19", p.Security.SAEPTGroup19)
+	setOptionalString(d, profileSectionSecurity, "SAE-PT-Group20", p.Security.SAEPTGroup20)
+	setEAPSettings(d, p.Security.EAP)
+
+	setOptionalBool(d, profileSectionSettings, "AutoConnect", p.Settings.AutoConnect)
+	setOptionalBool(d, profileSectionSettings, "Hidden", p.Settings.Hidden)
+	setOptionalBool(d, profileSectionSettings, "AlwaysRandomizeAddress", p.Settings.AlwaysRandomizeAddress)
+	setOptionalString(d, profileSectionSettings, "AddressOverride", p.Settings.AddressOverride)
+
+	setOptionalBool(d, profileSectionNetwork, "EnableIPv6", p.Network.EnableIPv6)
+	setOptionalString(d, profileSectionNetwork, "NameResolvingService", p.Network.NameResolvingService)
+
+	if p.IPv4 == nil {
+		for _, key := range []string{"Address", "Netmask", "Gateway", "Broadcast", "DNS", "DomainName"} {
+			d.unset(profileSectionIPv4, key)
+		}
+	} else {
+		d.set(profileSectionIPv4, "Address", p.IPv4.Address)
+		setOptionalString(d, profileSectionIPv4, "Netmask", p.IPv4.Netmask)
+		setOptionalString(d, profileSectionIPv4, "Gateway", p.IPv4.Gateway)
+		setOptionalString(d, profileSectionIPv4, "Broadcast", p.IPv4.Broadcast)
+		setOptionalList(d, profileSectionIPv4, "DNS", p.IPv4.DNS)
+		setOptionalString(d, profileSectionIPv4, "DomainName", p.IPv4.DomainName)
+	}
+	if p.IPv6 == nil {
+		for _, key := range []string{"Address", "Gateway", "DNS"} {
+			d.unset(profileSectionIPv6, key)
+		}
+	} else {
+		d.set(profileSectionIPv6, "Address", p.IPv6.Address)
+		setOptionalString(d, profileSectionIPv6, "Gateway", p.IPv6.Gateway)
+		setOptionalList(d, profileSectionIPv6, "DNS", p.IPv6.DNS)
+	}
+	return []byte(d.String()), nil
+}
+
+func WriteNetworkProfile(stateDir string, profile *NetworkProfile) (string, error) {
+	if stateDir == "" {
+		stateDir = DefaultStateDirectory
+	}
+	fileName, err := profile.FileName()
+	if err != nil {
+		log.Errorf("failed to get file name for network profile %s: %s", profile.Name, err)
+		return "", err
+	}
+	data, err := profile.Marshal()
+	if err != nil {
+		log.Errorf("failed to marshal network profile %s: %s", profile.Name, err)
+		return "", err
+	}
+	if err = os.MkdirAll(stateDir, networkProfileDirMode); err != nil {
+		log.Errorf("failed to create state directory %s: %s", stateDir, err)
+		return "", fmt.Errorf("failed to create state directory %s: %s", stateDir, err)
+	}
+	path := filepath.Join(stateDir, fileName)
+	if err = writeFileAtomic(path, data, networkProfileMode); err != nil {
+		log.Errorf("failed to write network profile %s: %s", path, err)
+		return "", err
+	}
+	log.Debugf("Wrote network profile %s to %s", profile.Name, path)
+	return path, nil
+}
+
+func ProfileFileName(ssid, netType string) (string, error) {
+	switch netType {
+	case NetworkTypeOpen, NetworkTypePSK, NetworkType8021X:
+	default:
+		return "", fmt.Errorf("%w: unknown network type %s", ErrInvalidNetworkProfile, netType)
+	}
+	if len(ssid) == 0 || len(ssid) > maxSSIDLength {
+		return "", fmt.Errorf("%w: SSID must be between 1 and %d bytes long; got %d", ErrInvalidNetworkProfile, maxSSIDLength, len(ssid))
+	}
+	if ssidIsVerbatim(ssid) {
+		return ssid + "." + netType, nil
+	}
+	return "=" + hex.EncodeToString([]byte(ssid)) + "." + netType, nil
+}
+
+func ssidIsVerbatim(ssid string) bool {
+	for _, c := range []byte(ssid) {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == ' ' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func setEAPSettings(d *iniDocument, eap map[string]string) {
+	for _, key := range d.keys(profileSectionSecurity) {
+		if _, ok := eap[key]; strings.HasPrefix(key, "EAP-") && !ok {
+			d.unset(profileSectionSecurity, key)
+		}
+	}
+	eapKeys := make([]string, 0, len(eap))
+	for key := range eap {
+		eapKeys = append(eapKeys, key)
+	}
+	sort.Strings(eapKeys)
+	for _, key := range eapKeys {
+		d.set(profileSectionSecurity, key, eap[key])
+	}
+}
+
+func setOptionalString(d *iniDocument, section, key string, value *string) {
+	if value == nil {
+		d.unset(section, key)
+	} else {
+		d.set(section, key, *value)
+	}
+}
+
+func setOptionalBool(d *iniDocument, section, key string, value *bool) {
+	if value == nil {
+		d.unset(section, key)
+		return
+	}
+	if e := d.entry(section, key); e != nil {
+		if current, err := parseProfileBool(e.value); err == nil && current == *value {
+			return
+		}
+	}
+	d.set(section, key, strconv.FormatBool(*value))
+}
+
+func setOptionalList(d *iniDocument, section, key string, values []string) {
+	if len(values) == 0 {
+		d.unset(section, key)
+		return
+	}
+	if e := d.entry(section, key); e != nil && slices.Equal(strings.Fields(e.value), values) {
+		return
+	}
+	d.set(section, key, strings.Join(values, " "))
+}
@@ -0,0 +1,288 @@
+package spider
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	eapMethods         = []string{"MD5", "TLS", "TTLS", "PEAP", "PWD", "SIM", "AKA", "AKA'", "MSCHAPV2", "GTC"}
+	eapTunneledMethods = []string{"Tunneled-PAP", "Tunneled-CHAP", "Tunneled-MSCHAP", "Tunneled-MSCHAPv2"}
+)
+
+func ParseNetworkProfile(name, netType string, data []byte) (*NetworkProfile, error) {
+	d, err := parseIniDocument(data)
+	if err != nil {
+		log.Errorf("failed to parse network profile %s: %s", name, err)
+		return nil, err
+	}
+	p := NewNetworkProfile(name, netType)
+	p.doc = d
+	for _, section := range d.sections {
+		for _, e := range section.entries {
+			if !e.isKey() {
+				continue
+			}
+			if err = p.parseEntry(section.name, e); err != nil {
+				log.Errorf("failed to parse network profile %s: %s", name, err)
+				return nil, err
+			}
+		}
+		if err = p.checkSection(section); err != nil {
+			log.Errorf("failed to parse network profile %s: %s", name, err)
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func LoadNetworkProfile(path string) (*NetworkProfile, error) {
+	name, netType, err := ParseProfileFileName(filepath.Base(path))
+	if err != nil {
+		log.Errorf("failed to parse network profile file name %s: %s", path, err)
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Errorf("failed to read network profile %s: %s", path, err)
+		return nil, err
+	}
+	p, err := ParseNetworkProfile(name, netType, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	log.Debugf("Loaded network profile %s from %s", p, path)
+	return p, nil
+}
+
+func KnownNetworkProfilePath(stateDir string, kn *KnownNetwork) (string, error) {
+	if stateDir == "" {
+		stateDir = DefaultStateDirectory
+	}
+	name, err := kn.GetName()
+	if err != nil {
+		return "", err
+	}
+	netType, err := kn.GetType()
+	if err != nil {
+		return "", err
+	}
+	fileName, err := ProfileFileName(name, netType)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, fileName), nil
+}
+
+func LoadKnownNetworkProfile(stateDir string, kn *KnownNetwork) (*NetworkProfile, error) {
+	path, err := KnownNetworkProfilePath(stateDir, kn)
+	if err != nil {
+		log.Errorf("failed to get network profile path for KnownNetwork %s: %s", kn.GetPath(), err)
+		return nil, err
+	}
+	return LoadNetworkProfile(path)
+}
+
+func (p *NetworkProfile) parseEntry(section string, e *iniEntry) error {
+	fail := func(format string, args ...interface{}) error {
+		return &IniParseError{Line: e.line, Message: fmt.Sprintf("[%s] %s: %s", section, e.key, fmt.Sprintf(format, args...))}
+	}
+	parseBool := func() (*bool, error) {
+		b, err := parseProfileBool(e.value)
+		if err != nil {
+			return nil, fail("%s", err)
+		}
+		return &b, nil
+	}
+	value := e.value
+	var err error
+	switch section {
+	case profileSectionSecurity:
+		switch {
+		case e.key == "Passphrase":
+			if err = validatePassphrase(value); err != nil {
+				return fail("%s", err)
+			}
+			p.Security.Passphrase = &value
+		case e.key == "PreSharedKey":
+			if err = validatePreSharedKey(value); err != nil {
+				return fail("%s", err)
+			}
+			p.Security.PreSharedKey = &value
+		case e.key == "SAE-PT-Group19":
+			if err = validateSAEPT(19, value); err != nil {
+				return fail("%s", err)
+			}
+			p.Security.SAEPTGroup19 = &value
+		case e.key == "SAE-PT-Group20":
+			if err = validateSAEPT(20, value); err != nil {
+				return fail("%s", err)
+			}
+			p.Security.SAEPTGroup20 = &value
+		case strings.HasPrefix(e.key, "EAP-"):
+			if err = validateEAPSetting(e.key, value); err != nil {
+				return fail("%s", err)
+			}
+			if p.Security.EAP == nil {
+				p.Security.EAP = make(map[string]string)
+			}
+			p.Security.EAP[e.key] = value
+		}
+	case profileSectionSettings:
+		switch e.key {
+		case "AutoConnect":
+			p.Settings.AutoConnect, err = parseBool()
+		case "Hidden":
+			p.Settings.Hidden, err = parseBool()
+		case "AlwaysRandomizeAddress":
+			p.Settings.AlwaysRandomizeAddress, err = parseBool()
+		case "AddressOverride":
+			p.Settings.AddressOverride = &value
+		}
+	case profileSectionNetwork:
+		switch e.key {
+		case "EnableIPv6":
+			p.Network.EnableIPv6, err = parseBool()
+		case "NameResolvingService":
+			p.Network.NameResolvingService = &value
+		}
+	case profileSectionIPv4:
+		if p.IPv4 == nil {
+			p.IPv4 = &NetworkProfileIPv4{}
+		}
+		switch e.key {
+		case "Address":
+			p.IPv4.Address = value
+		case "Netmask":
+			p.IPv4.Netmask = &value
+		case "Gateway":
+			p.IPv4.Gateway = &value
+		case "Broadcast":
+			p.IPv4.Broadcast = &value
+		case "DNS":
+			p.IPv4.DNS = strings.Fields(value)
+		case "DomainName":
+			p.IPv4.DomainName = &value
+		}
+	case profileSectionIPv6:
+		if p.IPv6 == nil {
+			p.IPv6 = &NetworkProfileIPv6{}
+		}
+		switch e.key {
+		case "Address":
+			p.IPv6.Address = value
+		case "Gateway":
+			p.IPv6.Gateway = &value
+		case "DNS":
+			p.IPv6.DNS = strings.Fields(value)
+		}
+	}
+	return err
+}
+
+func (p *NetworkProfile) checkSection(section *iniSection) error {
+	switch section.name {
+	case profileSectionIPv4:
+		if p.IPv4 != nil && p.IPv4.Address == "" {
+			return &IniParseError{Line: section.line, Message: "[IPv4] section requires an Address"}
+		}
+	case profileSectionIPv6:
+		if p.IPv6 != nil && p.IPv6.Address == "" {
+			return &IniParseError{Line: section.line, Message: "[IPv6] section requires an Address"}
+		}
+	}
+	return nil
+}
+
+func ParseProfileFileName(fileName string) (string, string, error) {
+	ext := filepath.Ext(fileName)
+	netType := strings.TrimPrefix(ext, ".")
+	switch netType {
+	case NetworkTypeOpen, NetworkTypePSK, NetworkType8021X:
+	default:
+		return "", "", fmt.Errorf("%w: unknown network type extension %q", ErrInvalidNetworkProfile, ext)
+	}
+	encoded := strings.TrimSuffix(fileName, ext)
+	ssid := encoded
+	if strings.HasPrefix(encoded, "=") {
+		decoded, err := hex.DecodeString(encoded[1:])
+		if err != nil {
+			return "", "", fmt.Errorf("%w: malformed hex SSID %q", ErrInvalidNetworkProfile, encoded)
+		}
+		ssid = string(decoded)
+	} else if !ssidIsVerbatim(encoded) {
+		return "", "", fmt.Errorf("%w: SSID %q must be hex encoded", ErrInvalidNetworkProfile, encoded)
+	}
+	if len(ssid) == 0 || len(ssid) > maxSSIDLength {
+		return "", "", fmt.Errorf("%w: SSID must be between 1 and %d bytes long; got %d", ErrInvalidNetworkProfile, maxSSIDLength, len(ssid))
+	}
+	return ssid, netType, nil
+}
+
+func parseProfileBool(value string) (bool, error) {
+	switch value {
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid boolean %q", value)
+	}
+}
+
+func validatePassphrase(passphrase string) error {
+	if len(passphrase) < 8 || len(passphrase) > 63 {
+		return fmt.Errorf("passphrase must be between 8 and 63 characters long; got %d", len(passphrase))
+	}
+	for _, c := range []byte(passphrase) {
+		if c < 32 || c > 126 {
+			return fmt.Errorf("passphrase must only contain printable ASCII characters")
+		}
+	}
+	return nil
+}
+
+func validatePreSharedKey(psk string) error {
+	if len(psk) != 64 {
+		return fmt.Errorf("pre-shared key must be 64 hex digits long; got %d", len(psk))
+	}
+	if _, err := hex.DecodeString(psk); err != nil {
+		return fmt.Errorf("pre-shared key must only contain hex digits")
+	}
+	return nil
+}
+
+func validateSAEPT(group int, pt string) error {
+	g, ok := saeGroups[group]
+	if !ok {
+		return fmt.Errorf("unsupported SAE group %d", group)
+	}
+	primeLen := (g.params.P.BitLen() + 7) / 8
+	if len(pt) != 4*primeLen {
+		return fmt.Errorf("SAE-PT-Group%d must be %d hex digits long; got %d", group, 4*primeLen, len(pt))
+	}
+	if _, err := hex.DecodeString(pt); err != nil {
+		return fmt.Errorf("SAE-PT-Group%d must only contain hex digits", group)
+	}
+	return nil
+}
+
+func validateEAPSetting(key, value string) error {
+	switch {
+	case key == "EAP-Method":
+		if !slices.Contains(eapMethods, value) {
+			return fmt.Errorf("unknown EAP method %q; valid methods are %v", value, eapMethods)
+		}
+	case strings.HasSuffix(key, "-Phase2-Method"):
+		if !slices.Contains(eapMethods, value) && !slices.Contains(eapTunneledMethods, value) {
+			return fmt.Errorf("unknown phase 2 method %q; valid methods are %v and %v", value, eapMethods, eapTunneledMethods)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package spider
+
+import (
+	"strings"
+	"testing"
+)
+
+const testRoundTripProfile = `# Written by hand; keep this comment
+; and this one
+
+[Security]
+# the passphrase
+Passphrase = correct horse
+X-Vendor-Key=keep me
+
+[Settings]  
+AutoConnect = 1
+Hidden=false
+TransitionDisable=true
+
+[IPv4]
+Netmask=255.255.255.0
+Address=192.168.1.10
+DNS=192.168.1.1   9.9.9.9
+
+[Unknown Section]
+Foo=bar
+`
+
+func parseTestRoundTripProfile(t *testing.T) *NetworkProfile {
+	t.Helper()
+	p, err := ParseNetworkProfile("Home", NetworkTypePSK, []byte(testRoundTripProfile))
+	if err != nil {
+		t.Fatalf("ParseNetworkProfile failed: %s", err)
+	}
+	return p
+}
+
+func changedLines(t *testing.T, before, after string) []string {
+	t.Helper()
+	beforeLines := strings.Split(before, "\n")
+	afterLines := strings.Split(after, "\n")
+	if len(beforeLines) != len(afterLines) {
+		t.Fatalf("line count changed from %d to %d:\n%s", len(beforeLines), len(afterLines), after)
+	}
+	changed := make([]string, 0)
+	for i := range beforeLines {
+		if beforeLines[i] != afterLines[i] {
+			changed = append(changed, afterLines[i])
+		}
+	}
+	return changed
+}
+
+func TestNetworkProfileRoundTripUnchanged(t *testing.T) {
+	p := parseTestRoundTripProfile(t)
+	if p.Security.Passphrase == nil || *p.Security.Passphrase != "correct horse" {
+		t.Errorf("Passphrase = %v; want correct horse", p.Security.Passphrase)
+	}
+	if p.Settings.AutoConnect == nil || !*p.Settings.AutoConnect {
+		t.Errorf("AutoConnect = %v; want true", p.Settings.AutoConnect)
+	}
+	if p.IPv4 == nil || p.IPv4.Address != "192.168.1.10" || len(p.IPv4.DNS) != 2 {
+		t.Errorf("IPv4 = %+v; want Address 192.168.1.10 with two DNS servers", p.IPv4)
+	}
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	if string(data) != testRoundTripProfile {
+		t.Errorf("unchanged round trip =\n%q\nwant\n%q", data, testRoundTripProfile)
+	}
+}
+
+func TestNetworkProfileRoundTripEdit(t *testing.T) {
+	p := parseTestRoundTripProfile(t)
+	passphrase := "battery staple"
+	p.Security.Passphrase = &passphrase
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	changed := changedLines(t, testRoundTripProfile, string(data))
+	if len(changed) != 1 || changed[0] != "Passphrase=battery staple" {
+		t.Errorf("changed lines = %q; want only the Passphrase line", changed)
+	}
+
+	p = parseTestRoundTripProfile(t)
+	hidden := true
+	p.Settings.Hidden = &hidden
+	p.IPv4.DNS = []string{"192.168.1.1"}
+	data, err = p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	changed = changedLines(t, testRoundTripProfile, string(data))
+	if expected := []string{"Hidden=true", "DNS=192.168.1.1"}; strings.Join(changed, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("changed lines = %q; want %q", changed, expected)
+	}
+}
+
+func TestNetworkProfileRoundTripAddAndRemove(t *testing.T) {
+	p := parseTestRoundTripProfile(t)
+	p.Settings.Hidden = nil
+	p.Network.EnableIPv6 = func() *bool { b := true; return &b }()
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	expected := strings.Replace(testRoundTripProfile, "Hidden=false\n", "", 1) + "\n[Network]\nEnableIPv6=true\n"
+	if string(data) != expected {
+		t.Errorf("edited profile =\n%s\nwant\n%s", data, expected)
+	}
+}
+
+func TestNetworkProfileRoundTripWithoutFinalNewline(t *testing.T) {
+	input := strings.TrimSuffix(testRoundTripProfile, "\n")
+	p, err := ParseNetworkProfile("Home", NetworkTypePSK, []byte(input))
+	if err != nil {
+		t.Fatalf("ParseNetworkProfile failed: %s", err)
+	}
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	if string(data) != input {
+		t.Errorf("unchanged round trip =\n%q\nwant\n%q", data, input)
+	}
+}
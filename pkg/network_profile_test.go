@@ -0,0 +1,277 @@
+package spider
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func stringPointer(s string) *string {
+	return &s
+}
+
+func TestNetworkProfileValidate(t *testing.T) {
+	psk := strings.Repeat("0f", 32)
+	tests := []struct {
+		name    string
+		profile func() *NetworkProfile
+		valid   bool
+	}{
+		{name: "open", valid: true, profile: func() *NetworkProfile {
+			return NewNetworkProfile("Cafe", NetworkTypeOpen)
+		}},
+		{name: "psk with passphrase", valid: true, profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Home", NetworkTypePSK)
+			p.Security.Passphrase = stringPointer("password")
+			return p
+		}},
+		{name: "psk with pre-shared key and SAE-PT", valid: true, profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Home", NetworkTypePSK)
+			p.Security.PreSharedKey = &psk
+			p.Security.SAEPTGroup19 = stringPointer(strings.Repeat("ab", 64))
+			p.Security.SAEPTGroup20 = stringPointer(strings.Repeat("ab", 96))
+			return p
+		}},
+		{name: "8021x", valid: true, profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Work", NetworkType8021X)
+			p.Security.EAP = map[string]string{"EAP-Method": "PEAP", "EAP-PEAP-Phase2-Method": "MSCHAPV2"}
+			return p
+		}},
+		{name: "32 byte SSID", valid: true, profile: func() *NetworkProfile {
+			return NewNetworkProfile(strings.Repeat("x", 32), NetworkTypeOpen)
+		}},
+		{name: "empty SSID", profile: func() *NetworkProfile {
+			return NewNetworkProfile("", NetworkTypeOpen)
+		}},
+		{name: "33 byte SSID", profile: func() *NetworkProfile {
+			return NewNetworkProfile(strings.Repeat("x", 33), NetworkTypeOpen)
+		}},
+		{name: "unknown type", profile: func() *NetworkProfile {
+			return NewNetworkProfile("Home", "wep")
+		}},
+		{name: "open with passphrase", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Cafe", NetworkTypeOpen)
+			p.Security.Passphrase = stringPointer("password")
+			return p
+		}},
+		{name: "open with EAP", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Cafe", NetworkTypeOpen)
+			p.Security.EAP = map[string]string{"EAP-Method": "TLS"}
+			return p
+		}},
+		{name: "psk without credentials", profile: func() *NetworkProfile {
+			return NewNetworkProfile("Home", NetworkTypePSK)
+		}},
+		{name: "short passphrase", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Home", NetworkTypePSK)
+			p.Security.Passphrase = stringPointer("short")
+			return p
+		}},
+		{name: "long passphrase", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Home", NetworkTypePSK)
+			p.Security.Passphrase = stringPointer(strings.Repeat("x", 64))
+			return p
+		}},
+		{name: "non-ASCII passphrase", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Home", NetworkTypePSK)
+			p.Security.Passphrase = stringPointer("pässwörd")
+			return p
+		}},
+		{name: "short pre-shared key", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Home", NetworkTypePSK)
+			p.Security.PreSharedKey = stringPointer(psk[:62])
+			return p
+		}},
+		{name: "non-hex pre-shared key", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Home", NetworkTypePSK)
+			p.Security.PreSharedKey = stringPointer(strings.Repeat("zz", 32))
+			return p
+		}},
+		{name: "SAE-PT of the wrong group size", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Home", NetworkTypePSK)
+			p.Security.Passphrase = stringPointer("password")
+			p.Security.SAEPTGroup19 = stringPointer(strings.Repeat("ab", 96))
+			return p
+		}},
+		{name: "8021x without EAP-Method", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Work", NetworkType8021X)
+			p.Security.EAP = map[string]string{"EAP-Identity": "user"}
+			return p
+		}},
+		{name: "8021x with unknown EAP-Method", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Work", NetworkType8021X)
+			p.Security.EAP = map[string]string{"EAP-Method": "LEAP"}
+			return p
+		}},
+		{name: "8021x with unknown phase 2 method", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Work", NetworkType8021X)
+			p.Security.EAP = map[string]string{"EAP-Method": "TTLS", "EAP-TTLS-Phase2-Method": "NOPE"}
+			return p
+		}},
+		{name: "IPv4 without Address", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Cafe", NetworkTypeOpen)
+			p.IPv4 = &NetworkProfileIPv4{Gateway: stringPointer("192.168.1.1")}
+			return p
+		}},
+		{name: "IPv6 without Address", profile: func() *NetworkProfile {
+			p := NewNetworkProfile("Cafe", NetworkTypeOpen)
+			p.IPv6 = &NetworkProfileIPv6{}
+			return p
+		}},
+	}
+	for _, tt := range tests {
+		err := tt.profile().Validate()
+		if tt.valid && err != nil {
+			t.Errorf("%s: Validate failed: %s", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidNetworkProfile) {
+			t.Errorf("%s: Validate error = %v; want %v", tt.name, err, ErrInvalidNetworkProfile)
+		}
+		if !tt.valid {
+			if _, err = tt.profile().Marshal(); !errors.Is(err, ErrInvalidNetworkProfile) {
+				t.Errorf("%s: Marshal error = %v; want %v", tt.name, err, ErrInvalidNetworkProfile)
+			}
+		}
+	}
+}
+
+func TestNetworkProfileMarshal(t *testing.T) {
+	autoConnect := false
+	p := NewNetworkProfile("Home", NetworkTypePSK)
+	p.Security.Passphrase = stringPointer("password")
+	p.Settings.AutoConnect = &autoConnect
+	p.IPv4 = &NetworkProfileIPv4{
+		Address: "192.168.1.10",
+		Netmask: stringPointer("255.255.255.0"),
+		DNS:     []string{"192.168.1.1", "9.9.9.9"},
+	}
+	data, err := p.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	expected := "[Security]\nPassphrase=password\n\n" +
+		"[Settings]\nAutoConnect=false\n\n" +
+		"[IPv4]\nAddress=192.168.1.10\nNetmask=255.255.255.0\nDNS=192.168.1.1 9.9.9.9\n"
+	if string(data) != expected {
+		t.Errorf("Marshal =\n%s\nwant\n%s", data, expected)
+	}
+}
+
+func TestWriteNetworkProfileRejectsInvalidProfile(t *testing.T) {
+	stateDir := t.TempDir()
+	if _, err := WriteNetworkProfile(stateDir, NewNetworkProfile("Home", NetworkTypePSK)); !errors.Is(err, ErrInvalidNetworkProfile) {
+		t.Fatalf("WriteNetworkProfile error = %v; want %v", err, ErrInvalidNetworkProfile)
+	}
+	entries, err := os.ReadDir(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("invalid profile left %d file(s) in %s", len(entries), stateDir)
+	}
+}
+
+func TestNetworkProfileDeriveCredentials(t *testing.T) {
+	for _, keepPassphrase := range []bool{false, true} {
+		p := NewNetworkProfile("IEEE", NetworkTypePSK)
+		p.Security.Passphrase = stringPointer("password")
+		if err := p.DeriveCredentials("password", keepPassphrase); err != nil {
+			t.Fatalf("DeriveCredentials failed: %s", err)
+		}
+		if keepPassphrase && (p.Security.Passphrase == nil || *p.Security.Passphrase != "password") {
+			t.Errorf("DeriveCredentials dropped the Passphrase although it was asked to keep it")
+		}
+		if !keepPassphrase && p.Security.Passphrase != nil {
+			t.Errorf("DeriveCredentials kept the Passphrase; want it removed")
+		}
+		if p.Security.PreSharedKey == nil || *p.Security.PreSharedKey != "f42c6fc52df0ebef9ebb4b90b38a5f902e83fe1b135a70e23aed762e9710a12e" {
+			t.Errorf("PreSharedKey = %v; want the derived key", p.Security.PreSharedKey)
+		}
+		if p.Security.SAEPTGroup19 == nil || p.Security.SAEPTGroup20 == nil {
+			t.Errorf("SAE-PT values were not derived")
+		}
+		if err := p.Validate(); err != nil {
+			t.Errorf("profile with derived credentials does not validate: %s", err)
+		}
+	}
+	if err := NewNetworkProfile("Open", NetworkTypeOpen).DeriveCredentials("password", false); !errors.Is(err, ErrInvalidNetworkProfile) {
+		t.Errorf("DeriveCredentials on an open network error = %v; want %v", err, ErrInvalidNetworkProfile)
+	}
+}
+
+func TestProfileFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		ssid     string
+		netType  string
+		fileName string
+	}{
+		{name: "plain ASCII", ssid: "Home_Net-5 G", netType: NetworkTypePSK, fileName: "Home_Net-5 G.psk"},
+		{name: "open", ssid: "Cafe", netType: NetworkTypeOpen, fileName: "Cafe.open"},
+		{name: "8021x", ssid: "Corp", netType: NetworkType8021X, fileName: "Corp.8021x"},
+		{name: "punctuation", ssid: "Bob's Wi-Fi!", netType: NetworkTypePSK, fileName: "=426f6227732057692d466921.psk"},
+		{name: "dot", ssid: "home.lan", netType: NetworkTypePSK, fileName: "=686f6d652e6c616e.psk"},
+		{name: "leading equals sign", ssid: "=abc", netType: NetworkTypePSK, fileName: "=3d616263.psk"},
+		{name: "slash", ssid: "a/b", netType: NetworkTypeOpen, fileName: "=612f62.open"},
+		{name: "UTF-8", ssid: "Café", netType: NetworkTypePSK, fileName: "=436166c3a9.psk"},
+		{name: "non UTF-8 bytes", ssid: "\xff\xfe\x00", netType: NetworkTypePSK, fileName: "=fffe00.psk"},
+		{name: "32 bytes verbatim", ssid: strings.Repeat("s", 32), netType: NetworkTypePSK, fileName: strings.Repeat("s", 32) + ".psk"},
+		{name: "32 bytes hex", ssid: strings.Repeat(".", 32), netType: NetworkTypePSK, fileName: "=" + strings.Repeat("2e", 32) + ".psk"},
+	}
+	for _, tt := range tests {
+		fileName, err := ProfileFileName(tt.ssid, tt.netType)
+		if err != nil {
+			t.Errorf("%s: ProfileFileName failed: %s", tt.name, err)
+			continue
+		}
+		if fileName != tt.fileName {
+			t.Errorf("%s: ProfileFileName(%q, %s) = %q; want %q", tt.name, tt.ssid, tt.netType, fileName, tt.fileName)
+		}
+		ssid, netType, err := ParseProfileFileName(fileName)
+		if err != nil {
+			t.Errorf("%s: ParseProfileFileName(%q) failed: %s", tt.name, fileName, err)
+			continue
+		}
+		if ssid != tt.ssid || netType != tt.netType {
+			t.Errorf("%s: ParseProfileFileName(%q) = %q, %s; want %q, %s", tt.name, fileName, ssid, netType, tt.ssid, tt.netType)
+		}
+	}
+}
+
+func TestProfileFileNameErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		ssid    string
+		netType string
+	}{
+		{name: "empty SSID", ssid: "", netType: NetworkTypePSK},
+		{name: "33 bytes", ssid: strings.Repeat("s", 33), netType: NetworkTypePSK},
+		{name: "33 bytes hex", ssid: strings.Repeat(".", 33), netType: NetworkTypePSK},
+		{name: "unknown type", ssid: "Home", netType: "wep"},
+	}
+	for _, tt := range tests {
+		if _, err := ProfileFileName(tt.ssid, tt.netType); !errors.Is(err, ErrInvalidNetworkProfile) {
+			t.Errorf("%s: ProfileFileName error = %v; want %v", tt.name, err, ErrInvalidNetworkProfile)
+		}
+	}
+}
+
+func TestParseProfileFileNameErrors(t *testing.T) {
+	for _, fileName := range []string{
+		".psk",
+		"=.psk",
+		"Home.wep",
+		"Home",
+		"=zz.psk",
+		"=abc.psk",
+		"home.lan.psk",
+		"Bob's.psk",
+		strings.Repeat("s", 33) + ".psk",
+		"=" + strings.Repeat("2e", 33) + ".psk",
+	} {
+		if _, _, err := ParseProfileFileName(fileName); !errors.Is(err, ErrInvalidNetworkProfile) {
+			t.Errorf("ParseProfileFileName(%q) error = %v; want %v", fileName, err, ErrInvalidNetworkProfile)
+		}
+	}
+}
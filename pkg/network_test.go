@@ -0,0 +1,198 @@
+package spider
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	testAdapterPath      = dbus.ObjectPath("/net/connman/iwd/0")
+	testDevicePath       = dbus.ObjectPath("/net/connman/iwd/0/3")
+	testNetworkPath      = dbus.ObjectPath("/net/connman/iwd/0/3/486f6d65_psk")
+	testKnownNetworkPath = dbus.ObjectPath("/net/connman/iwd/486f6d65_psk")
+	testBSSPath          = dbus.ObjectPath("/net/connman/iwd/0/3/486f6d65_psk/aabbccddeeff")
+	testOtherBSSPath     = dbus.ObjectPath("/net/connman/iwd/0/3/486f6d65_psk/112233445566")
+)
+
+func testNetworkObjects() testObjects {
+	return testObjects{
+		testAdapterPath: {
+			adapterInterface: {
+				"Powered":        true,
+				"Name":           "phy0",
+				"Model":          "Test Model",
+				"Vendor":         "Test Vendor",
+				"SupportedModes": []string{"station", "ap"},
+			},
+		},
+		testDevicePath: {
+			deviceInterface: {
+				"Name":    "wlan0",
+				"Address": "02:00:00:00:00:01",
+				"Powered": true,
+				"Adapter": testAdapterPath,
+				"Mode":    "station",
+			},
+		},
+		testNetworkPath: {
+			networkInterface: {
+				"Name":               "Home",
+				"Connected":          false,
+				"Device":             testDevicePath,
+				"Type":               NetworkTypePSK,
+				"KnownNetwork":       testKnownNetworkPath,
+				"ExtendedServiceSet": []dbus.ObjectPath{testBSSPath},
+			},
+		},
+		testKnownNetworkPath: {
+			knownNetworkInterface: {
+				"Name":        "Home",
+				"Type":        NetworkTypePSK,
+				"Hidden":      false,
+				"AutoConnect": true,
+			},
+		},
+		testBSSPath: {
+			basicServiceSetInterface: {
+				"Address": "AA:BB:CC:DD:EE:FF",
+			},
+		},
+		testOtherBSSPath: {
+			basicServiceSetInterface: {
+				"Address": "11:22:33:44:55:66",
+			},
+		},
+	}
+}
+
+func TestNetworkAutoRefreshUpdatesOnlyChangedProperties(t *testing.T) {
+	client, server := newTestConnPair(t)
+	exportTestBus(t, server)
+	props := exportTestObjects(t, server, testNetworkObjects())
+	n, err := NewNetwork(client, testNetworkPath)
+	if err != nil {
+		t.Fatalf("NewNetwork failed: %s", err)
+	}
+	if err = n.SetAutoRefresh(true); err != nil {
+		t.Fatalf("SetAutoRefresh failed: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = n.SetAutoRefresh(false)
+	})
+	kn := n.GetKnownNetwork()
+	if kn == nil {
+		t.Fatal("KnownNetwork was not loaded")
+	}
+
+	props[testDevicePath].SetMust(deviceInterface, "Name", "wlan1")
+	props[testNetworkPath].SetMust(networkInterface, "Connected", true)
+	emitTestPropertiesChanged(t, server, testNetworkPath, networkInterface, map[string]dbus.Variant{
+		"Connected": dbus.MakeVariant(true),
+	}, nil)
+	waitForTestCondition(t, "Connected to change", n.GetConnected)
+	if n.GetKnownNetwork() != kn {
+		t.Errorf("KnownNetwork was rebuilt after an unrelated property changed")
+	}
+	if device := n.GetDevice(); device.GetName() != "wlan0" {
+		t.Errorf("Device was rebuilt after an unrelated property changed; Name = %s", device.GetName())
+	}
+
+	props[testBSSPath].SetMust(basicServiceSetInterface, "Address", "AA:AA:AA:AA:AA:AA")
+	props[testNetworkPath].SetMust(networkInterface, "ExtendedServiceSet", []dbus.ObjectPath{testBSSPath, testOtherBSSPath})
+	emitTestPropertiesChanged(t, server, testNetworkPath, networkInterface, map[string]dbus.Variant{
+		"ExtendedServiceSet": dbus.MakeVariant([]dbus.ObjectPath{testBSSPath, testOtherBSSPath}),
+	}, nil)
+	waitForTestCondition(t, "ExtendedServiceSet to change", func() bool {
+		ess := n.GetExtendedServiceSet()
+		return ess != nil && len(*ess) == 2
+	})
+	ess := *n.GetExtendedServiceSet()
+	if address := ess[0].GetAddress(); address != "AA:BB:CC:DD:EE:FF" {
+		t.Errorf("existing Basic Service Set was rebuilt; Address = %s", address)
+	}
+	if address := ess[1].GetAddress(); address != "11:22:33:44:55:66" {
+		t.Errorf("new Basic Service Set Address = %s; want 11:22:33:44:55:66", address)
+	}
+
+	props[testNetworkPath].SetMust(networkInterface, "Name", "Office")
+	emitTestPropertiesChanged(t, server, testNetworkPath, networkInterface, map[string]dbus.Variant{}, []string{"Name"})
+	waitForTestCondition(t, "invalidated Name to be fetched", func() bool {
+		name, _ := n.GetName()
+		return name == "Office"
+	})
+	if n.GetKnownNetwork() != kn {
+		t.Errorf("KnownNetwork was rebuilt after an unrelated property was invalidated")
+	}
+}
+
+func TestNetworkExtendedServiceSetEnrichment(t *testing.T) {
+	for _, developerMode := range []bool{false, true} {
+		client, server := newTestConnPair(t)
+		objects := testNetworkObjects()
+		objects[testNetworkPath][networkInterface]["ExtendedServiceSet"] = []dbus.ObjectPath{testBSSPath, testOtherBSSPath}
+		objects[testDevicePath][stationInterface] = map[string]interface{}{
+			"ConnectedAccessPoint": testBSSPath,
+		}
+		if developerMode {
+			objects[testDevicePath][stationDebugInterface] = map[string]interface{}{}
+			if err := server.ExportMethodTable(map[string]interface{}{
+				"GetNetworks": func() (map[dbus.ObjectPath][]map[string]dbus.Variant, *dbus.Error) {
+					return map[dbus.ObjectPath][]map[string]dbus.Variant{
+						testNetworkPath: {
+							{"Address": dbus.MakeVariant("aa:bb:cc:dd:ee:ff"), "Frequency": dbus.MakeVariant(uint32(2412)), "RSSI": dbus.MakeVariant(int16(-6000))},
+							{"Address": dbus.MakeVariant("11:22:33:44:55:66"), "Frequency": dbus.MakeVariant(uint32(5955)), "RSSI": dbus.MakeVariant(int16(-7000))},
+						},
+					}, nil
+				},
+			}, testDevicePath, stationDebugInterface); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := server.ExportMethodTable(map[string]interface{}{
+			"GetDiagnostics": func() (map[string]dbus.Variant, *dbus.Error) {
+				return map[string]dbus.Variant{
+					"Frequency": dbus.MakeVariant(uint32(5180)),
+					"RSSI":      dbus.MakeVariant(int16(-50)),
+				}, nil
+			},
+		}, testDevicePath, stationDiagnosticInterface); err != nil {
+			t.Fatal(err)
+		}
+		exportTestObjects(t, server, objects)
+
+		n, err := NewNetwork(client, testNetworkPath)
+		if err != nil {
+			t.Fatalf("NewNetwork failed: %s", err)
+		}
+		ess := *n.GetExtendedServiceSet()
+		connected, other := ess[0], ess[1]
+		if !connected.GetConnected() || other.GetConnected() {
+			t.Errorf("developer mode %t: connected flags = %t, %t; want true, false", developerMode, connected.GetConnected(), other.GetConnected())
+		}
+		if frequency := connected.GetFrequency(); frequency == nil || *frequency != 5180 {
+			t.Errorf("developer mode %t: connected Frequency = %v; want 5180", developerMode, frequency)
+		}
+		if channel := connected.GetChannel(); channel == nil || *channel != 36 {
+			t.Errorf("developer mode %t: connected Channel = %v; want 36", developerMode, channel)
+		}
+		if signal := connected.GetSignalStrength(); signal == nil || *signal != -5000 {
+			t.Errorf("developer mode %t: connected SignalStrength = %v; want -5000", developerMode, signal)
+		}
+		if !developerMode {
+			if other.GetFrequency() != nil || other.GetChannel() != nil || other.GetSignalStrength() != nil || other.GetBand() != "" {
+				t.Errorf("without StationDebug the unconnected Basic Service Set was enriched: %s", &other)
+			}
+			continue
+		}
+		if frequency := other.GetFrequency(); frequency == nil || *frequency != 5955 {
+			t.Errorf("unconnected Frequency = %v; want 5955", frequency)
+		}
+		if channel := other.GetChannel(); channel == nil || *channel != 1 || other.GetBand() != "6 GHz" {
+			t.Errorf("unconnected Channel = %v, Band = %s; want 1, 6 GHz", channel, other.GetBand())
+		}
+		if signal := other.GetSignalStrength(); signal == nil || *signal != -7000 {
+			t.Errorf("unconnected SignalStrength = %v; want -7000", signal)
+		}
+	}
+}
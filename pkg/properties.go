@@ -0,0 +1,71 @@
+package spider
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	propertiesInterface               = "org.freedesktop.DBus.Properties"
+	propertiesSignalPropertiesChanged = propertiesInterface + ".PropertiesChanged"
+)
+
+type propertiesChange struct {
+	changed     map[string]dbus.Variant
+	invalidated []string
+}
+
+func watchPropertiesChanged(conn *dbus.Conn, path dbus.ObjectPath, iface string) (<-chan *propertiesChange, func(), error) {
+	options := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(propertiesInterface),
+		dbus.WithMatchMember("PropertiesChanged"),
+		dbus.WithMatchArg(0, iface),
+	}
+	if err := conn.AddMatchSignal(options...); err != nil {
+		log.Errorf("failed to add PropertiesChanged match for %s on %s: %s", iface, path, err)
+		return nil, nil, err
+	}
+	signals := make(chan *dbus.Signal, 16)
+	changes := make(chan *propertiesChange, 16)
+	done := make(chan struct{})
+	conn.Signal(signals)
+	go func() {
+		defer close(changes)
+		for {
+			select {
+			case <-done:
+				return
+			case sig, ok := <-signals:
+				if !ok {
+					return
+				}
+				if sig.Path != path || sig.Name != propertiesSignalPropertiesChanged || len(sig.Body) < 3 {
+					continue
+				}
+				if name, ok := sig.Body[0].(string); !ok || name != iface {
+					continue
+				}
+				change := &propertiesChange{}
+				change.changed, _ = sig.Body[1].(map[string]dbus.Variant)
+				change.invalidated, _ = sig.Body[2].([]string)
+				select {
+				case changes <- change:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			conn.RemoveSignal(signals)
+			_ = conn.RemoveMatchSignal(options...)
+			close(done)
+		})
+	}
+	return changes, stop, nil
+}
@@ -0,0 +1,155 @@
+package spider
+
+import (
+	"crypto/elliptic"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	pskIterations = 4096
+	pskLength     = 32
+	saeLabelU1P1  = "SAE Hash to Element u1 P1"
+	saeLabelU2P2  = "SAE Hash to Element u2 P2"
+)
+
+type saeGroup struct {
+	params *elliptic.CurveParams
+	z      int64
+	hash   func() hash.Hash
+}
+
+var (
+	SAEGroups = []int{19, 20}
+	saeGroups = map[int]*saeGroup{
+		19: {params: elliptic.P256().Params(), z: -10, hash: sha256.New},
+		20: {params: elliptic.P384().Params(), z: -12, hash: sha512.New384},
+	}
+)
+
+func DerivePreSharedKey(ssid, passphrase string) (string, error) {
+	if len(ssid) == 0 || len(ssid) > maxSSIDLength {
+		return "", fmt.Errorf("SSID must be between 1 and 32 bytes long")
+	}
+	if err := validatePassphrase(passphrase); err != nil {
+		return "", err
+	}
+	key := pbkdf2.Key([]byte(passphrase), []byte(ssid), pskIterations, pskLength, sha1.New)
+	return hex.EncodeToString(key), nil
+}
+
+func DeriveSAEPT(group int, ssid, password string) (string, error) {
+	g, ok := saeGroups[group]
+	if !ok {
+		return "", fmt.Errorf("unsupported SAE group %d; supported groups are %v", group, SAEGroups)
+	}
+	if len(ssid) == 0 || len(ssid) > maxSSIDLength {
+		return "", fmt.Errorf("SSID must be between 1 and 32 bytes long")
+	}
+	if len(password) == 0 {
+		return "", fmt.Errorf("SAE password must not be empty")
+	}
+	primeLen := (g.params.P.BitLen() + 7) / 8
+	valueLen := primeLen + (primeLen+1)/2
+	seed := hkdf.Extract(g.hash, []byte(password), []byte(ssid))
+	points := make([][2]*big.Int, 0, 2)
+	for _, label := range []string{saeLabelU1P1, saeLabelU2P2} {
+		value := make([]byte, valueLen)
+		if _, err := io.ReadFull(hkdf.Expand(g.hash, seed, []byte(label)), value); err != nil {
+			return "", fmt.Errorf("failed to expand SAE password seed: %s", err)
+		}
+		u := new(big.Int).SetBytes(value)
+		u.Mod(u, g.params.P)
+		x, y := g.sswu(u)
+		points = append(points, [2]*big.Int{x, y})
+	}
+	x, y, err := g.add(points[0][0], points[0][1], points[1][0], points[1][1])
+	if err != nil {
+		return "", err
+	}
+	pt := make([]byte, 2*primeLen)
+	x.FillBytes(pt[:primeLen])
+	y.FillBytes(pt[primeLen:])
+	return hex.EncodeToString(pt), nil
+}
+
+func (g *saeGroup) sswu(u *big.Int) (*big.Int, *big.Int) {
+	p := g.params.P
+	a := new(big.Int).Sub(p, big.NewInt(3))
+	b := g.params.B
+	z := new(big.Int).Mod(big.NewInt(g.z), p)
+
+	zu2 := new(big.Int).Mul(u, u)
+	zu2.Mul(zu2, z).Mod(zu2, p)
+	tv1 := new(big.Int).Mul(zu2, zu2)
+	tv1.Add(tv1, zu2).Mod(tv1, p)
+
+	x1 := new(big.Int)
+	if tv1.Sign() == 0 {
+		za := new(big.Int).Mul(z, a)
+		x1.Mul(b, za.ModInverse(za.Mod(za, p), p)).Mod(x1, p)
+	} else {
+		inv := new(big.Int).ModInverse(tv1, p)
+		inv.Add(inv, big.NewInt(1))
+		negB := new(big.Int).Sub(p, b)
+		x1.ModInverse(a, p)
+		x1.Mul(x1, negB).Mul(x1, inv).Mod(x1, p)
+	}
+	x2 := new(big.Int).Mul(zu2, x1)
+	x2.Mod(x2, p)
+
+	x := x1
+	y := new(big.Int).ModSqrt(g.curveRHS(x1), p)
+	if y == nil {
+		x = x2
+		y = new(big.Int).ModSqrt(g.curveRHS(x2), p)
+	}
+	if u.Bit(0) != y.Bit(0) {
+		y.Sub(p, y)
+	}
+	return x, y
+}
+
+func (g *saeGroup) curveRHS(x *big.Int) *big.Int {
+	p := g.params.P
+	rhs := new(big.Int).Mul(x, x)
+	rhs.Mul(rhs, x)
+	threeX := new(big.Int).Lsh(x, 1)
+	threeX.Add(threeX, x)
+	rhs.Sub(rhs, threeX)
+	rhs.Add(rhs, g.params.B)
+	return rhs.Mod(rhs, p)
+}
+
+func (g *saeGroup) add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int, error) {
+	p := g.params.P
+	lambda := new(big.Int)
+	if x1.Cmp(x2) == 0 {
+		if y1.Cmp(y2) != 0 || y1.Sign() == 0 {
+			return nil, nil, fmt.Errorf("SAE password element is the point at infinity")
+		}
+		num := new(big.Int).Mul(x1, x1)
+		num.Mul(num, big.NewInt(3)).Sub(num, big.NewInt(3))
+		den := new(big.Int).Lsh(y1, 1)
+		lambda.Mul(num, den.ModInverse(den.Mod(den, p), p))
+	} else {
+		num := new(big.Int).Sub(y2, y1)
+		den := new(big.Int).Sub(x2, x1)
+		lambda.Mul(num, den.ModInverse(den.Mod(den, p), p))
+	}
+	lambda.Mod(lambda, p)
+	x3 := new(big.Int).Mul(lambda, lambda)
+	x3.Sub(x3, x1).Sub(x3, x2).Mod(x3, p)
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, lambda).Sub(y3, y1).Mod(y3, p)
+	return x3, y3, nil
+}
@@ -0,0 +1,161 @@
+package spider
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestDerivePreSharedKey(t *testing.T) {
+	tests := []struct {
+		ssid       string
+		passphrase string
+		psk        string
+	}{
+		{
+			ssid:       "IEEE",
+			passphrase: "password",
+			psk:        "f42c6fc52df0ebef9ebb4b90b38a5f902e83fe1b135a70e23aed762e9710a12e",
+		},
+		{
+			ssid:       "ThisIsASSID",
+			passphrase: "ThisIsAPassword",
+			psk:        "0dc0d6eb90555ed6419756b9a15ec3e3209b63df707dd508d14581f8982721af",
+		},
+	}
+	for _, tt := range tests {
+		psk, err := DerivePreSharedKey(tt.ssid, tt.passphrase)
+		if err != nil {
+			t.Fatalf("DerivePreSharedKey(%q, %q) failed: %s", tt.ssid, tt.passphrase, err)
+		}
+		if psk != tt.psk {
+			t.Errorf("DerivePreSharedKey(%q, %q) = %s; want %s", tt.ssid, tt.passphrase, psk, tt.psk)
+		}
+	}
+}
+
+func TestDerivePreSharedKeyErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		ssid       string
+		passphrase string
+	}{
+		{name: "passphrase too short", ssid: "IEEE", passphrase: "passwor"},
+		{name: "passphrase too long", ssid: "IEEE", passphrase: strings.Repeat("a", 64)},
+		{name: "empty SSID", ssid: "", passphrase: "password"},
+		{name: "SSID too long", ssid: strings.Repeat("s", 33), passphrase: "password"},
+	}
+	for _, tt := range tests {
+		if _, err := DerivePreSharedKey(tt.ssid, tt.passphrase); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestDeriveSAEPT(t *testing.T) {
+	pt, err := DeriveSAEPT(19, "byteme", "mekmitasdigoat"+"psk4internet")
+	if err != nil {
+		t.Fatalf("DeriveSAEPT failed: %s", err)
+	}
+	expected := "b6e38c98750c684b5d17c3d8c9a4100b39931279187ca6cced5f37ef46ddfa97" +
+		"5687e972e50f73e3898861e7edad21bea7d5f622df88243bb804920ae8e647fa"
+	if pt != expected {
+		t.Errorf("DeriveSAEPT = %s; want %s", pt, expected)
+	}
+	if err = validateSAEPT(19, pt); err != nil {
+		t.Errorf("derived PT does not validate: %s", err)
+	}
+	pt, err = DeriveSAEPT(20, "byteme", "mekmitasdigoat"+"psk4internet")
+	if err != nil {
+		t.Fatalf("DeriveSAEPT group 20 failed: %s", err)
+	}
+	expected = "c20f7de2ff2c6a2482c81aeaa525fb969c0897cec0f05f32942c3dcd4f3a3c83ac68a9ad918eb4b0ac068c9fef93f584" +
+		"7e9bc499f475bc3fe4f345bb14007dabdc7568f7f74f3e5dbb046475903736a395f3570d2c778dc96641d8d2910c75e8"
+	if pt != expected {
+		t.Errorf("DeriveSAEPT group 20 = %s; want %s", pt, expected)
+	}
+	if err = validateSAEPT(20, pt); err != nil {
+		t.Errorf("derived group 20 PT does not validate: %s", err)
+	}
+}
+
+func TestSAESimplifiedSWU(t *testing.T) {
+	// map_to_curve vectors from RFC 9380 appendix J.1.1 (P256_XMD:SHA-256_SSWU_RO_)
+	// and J.2.1 (P384_XMD:SHA-384_SSWU_RO_), which use the same Z as groups 19 and 20.
+	tests := []struct {
+		group int
+		u     string
+		x     string
+		y     string
+	}{
+		{
+			group: 19,
+			u:     "ad5342c66a6dd0ff080df1da0ea1c04b96e0330dd89406465eeba11582515009",
+			x:     "ab640a12220d3ff283510ff3f4b1953d09fad35795140b1c5d64f313967934d5",
+			y:     "dccb558863804a881d4fff3455716c836cef230e5209594ddd33d85c565b19b1",
+		},
+		{
+			group: 19,
+			u:     "8c0f1d43204bd6f6ea70ae8013070a1518b43873bcd850aafa0a9e220e2eea5a",
+			x:     "51cce63c50d972a6e51c61334f0f4875c9ac1cd2d3238412f84e31da7d980ef5",
+			y:     "b45d1a36d00ad90e5ec7840a60a4de411917fbe7c82c3949a6e699e5a1b66aac",
+		},
+		{
+			group: 19,
+			u:     "afe47f2ea2b10465cc26ac403194dfb68b7f5ee865cda61e9f3e07a537220af1",
+			x:     "5219ad0ddef3cc49b714145e91b2f7de6ce0a7a7dc7406c7726c7e373c58cb48",
+			y:     "7950144e52d30acbec7b624c203b1996c99617d0b61c2442354301b191d93ecf",
+		},
+		{
+			group: 20,
+			u:     "25c8d7dc1acd4ee617766693f7f8829396065d1b447eedb155871feffd9c6653279ac7e5c46edb7010a0e4ff64c9f3b4",
+			x:     "e4717e29eef38d862bee4902a7d21b44efb58c464e3e1f0d03894d94de310f8ffc6de86786dd3e15a1541b18d4eb2846",
+			y:     "6b95a6e639822312298a47526bb77d9cd7bcf76244c991c8cd70075e2ee6e8b9a135c4a37e3c0768c7ca871c0ceb53d4",
+		},
+		{
+			group: 20,
+			u:     "59428be4ed69131df59a0c6a8e188d2d4ece3f1b2a3a02602962b47efa4d7905945b1e2cc80b36aa35c99451073521ac",
+			x:     "509527cfc0750eedc53147e6d5f78596c8a3b7360e0608e2fab0563a1670d58d8ae107c9f04bcf90e89489ace5650efd",
+			y:     "33337b13cb35e173fdea4cb9e8cce915d836ff57803dbbeb7998aa49d17df2ff09b67031773039d09fbd9305a1566bc4",
+		},
+		{
+			group: 20,
+			u:     "53350214cb6bef0b51abb791b1c4209a2b4c16a0c67e1ab1401017fad774cd3b3f9a8bcdf7f6229dd8dd5a075cb149a0",
+			x:     "fc853b69437aee9a19d5acf96a4ee4c5e04cf7b53406dfaa2afbdd7ad2351b7f554e4bbc6f5db4177d4d44f933a8f6ee",
+			y:     "7e042547e01834c9043b10f3a8221c4a879cb156f04f72bfccab0c047a304e30f2aa8b2e260d34c4592c0c33dd0c6482",
+		},
+		{
+			group: 20,
+			u:     "c0473083898f63e03f26f14877a2407bd60c75ad491e7d26cbc6cc5ce815654075ec6b6898c7a41d74ceaf720a10c02e",
+			x:     "57912293709b3556b43a2dfb137a315d256d573b82ded120ef8c782d607c05d930d958e50cb6dc1cc480b9afc38c45f1",
+			y:     "de9387dab0eef0bda219c6f168a92645a84665c4f2137c14270fb424b7532ff84843c3da383ceea24c47fa343c227bb8",
+		},
+	}
+	for _, tt := range tests {
+		u, _ := new(big.Int).SetString(tt.u, 16)
+		x, y := saeGroups[tt.group].sswu(u)
+		if hex.EncodeToString(x.Bytes()) != tt.x || hex.EncodeToString(y.Bytes()) != tt.y {
+			t.Errorf("group %d sswu(%s) = (%x, %x); want (%s, %s)", tt.group, tt.u, x, y, tt.x, tt.y)
+		}
+	}
+}
+
+func TestDeriveSAEPTErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		group    int
+		ssid     string
+		password string
+	}{
+		{name: "unsupported group", group: 21, ssid: "byteme", password: "mekmitasdigoat"},
+		{name: "empty SSID", group: 19, ssid: "", password: "mekmitasdigoat"},
+		{name: "SSID too long", group: 19, ssid: strings.Repeat("s", 33), password: "mekmitasdigoat"},
+		{name: "empty password", group: 19, ssid: "byteme", password: ""},
+	}
+	for _, tt := range tests {
+		if _, err := DeriveSAEPT(tt.group, tt.ssid, tt.password); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package spider
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultResolvConfPath  = "/etc/resolv.conf"
+	resolvConfBackupSuffix = ".spider.bak"
+	resolvConfMode         = 0644
+	resolvConfMaxServers   = 3
+)
+
+type ResolvConfApplier struct {
+	mu         sync.Mutex
+	path       string
+	backupPath string
+	backedUp   bool
+	existed    bool
+	devices    map[dbus.ObjectPath]map[AddressFamily]*dnsSettings
+	logger     *log.Entry
+}
+
+func NewResolvConfApplier(path string) *ResolvConfApplier {
+	if path == "" {
+		path = DefaultResolvConfPath
+	}
+	return &ResolvConfApplier{
+		path:       path,
+		backupPath: path + resolvConfBackupSuffix,
+		devices:    make(map[dbus.ObjectPath]map[AddressFamily]*dnsSettings),
+		logger: log.WithFields(log.Fields{
+			"type": "ResolvConfApplier",
+			"path": path,
+		}),
+	}
+}
+
+func (a *ResolvConfApplier) ApplyDNS(device dbus.ObjectPath, family AddressFamily, config *NetworkConfigurationConfig) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.backup(); err != nil {
+		return err
+	}
+	if _, ok := a.devices[device]; !ok {
+		a.devices[device] = make(map[AddressFamily]*dnsSettings)
+	}
+	a.devices[device][family] = newDNSSettings(config)
+	if config.MDNS != nil {
+		a.logger.Debugf("Ignoring MDNS setting %s for Device %s; resolv.conf cannot express it", *config.MDNS, device)
+	}
+	return a.write()
+}
+
+func (a *ResolvConfApplier) RevertDNS(device dbus.ObjectPath, family AddressFamily) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if families, ok := a.devices[device]; ok {
+		delete(families, family)
+		if len(families) == 0 {
+			delete(a.devices, device)
+		}
+	}
+	if len(a.devices) == 0 {
+		return a.restore()
+	}
+	return a.write()
+}
+
+func (a *ResolvConfApplier) backup() error {
+	if a.backedUp {
+		return nil
+	}
+	if _, err := os.Lstat(a.backupPath); err == nil {
+		a.logger.Warnf("Reusing existing backup %s", a.backupPath)
+		a.backedUp = true
+		a.existed = true
+		return nil
+	}
+	if err := os.Link(a.path, a.backupPath); err != nil {
+		if !os.IsNotExist(err) {
+			a.logger.WithFields(log.Fields{
+				"err": err,
+			}).Errorf("Failed to back up to %s", a.backupPath)
+			return fmt.Errorf("failed to back up %s: %s", a.path, err)
+		}
+		a.existed = false
+	} else {
+		a.existed = true
+	}
+	a.backedUp = true
+	a.logger.Debugf("Backed up to %s", a.backupPath)
+	return nil
+}
+
+func (a *ResolvConfApplier) restore() error {
+	if !a.backedUp {
+		return nil
+	}
+	var err error
+	if a.existed {
+		err = os.Rename(a.backupPath, a.path)
+	} else {
+		err = os.Remove(a.path)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		a.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to restore")
+		return fmt.Errorf("failed to restore %s: %s", a.path, err)
+	}
+	a.backedUp = false
+	a.logger.Debug("Restored")
+	return nil
+}
+
+func (a *ResolvConfApplier) write() error {
+	devices := make([]string, 0, len(a.devices))
+	for device := range a.devices {
+		devices = append(devices, string(device))
+	}
+	sort.Strings(devices)
+	all := make([]*dnsSettings, 0)
+	for _, device := range devices {
+		all = append(all, orderedDNSSettings(a.devices[dbus.ObjectPath(device)])...)
+	}
+	settings := mergeDNSSettings(all...)
+	unmanaged, err := a.unmanagedLines()
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("# Generated by spider\n")
+	if len(settings.domains) > 0 {
+		fmt.Fprintf(&b, "search %s\n", strings.Join(settings.domains, " "))
+	}
+	if len(settings.servers) > resolvConfMaxServers {
+		a.logger.Warnf("resolv.conf only supports %d name servers; %d configured", resolvConfMaxServers, len(settings.servers))
+	}
+	for _, server := range settings.servers {
+		fmt.Fprintf(&b, "nameserver %s\n", server)
+	}
+	for _, line := range unmanaged {
+		fmt.Fprintf(&b, "%s\n", line)
+	}
+	if err = writeFileAtomic(a.path, []byte(b.String()), resolvConfMode); err != nil {
+		a.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to write")
+		return err
+	}
+	a.logger.Debugf("Wrote %d name server(s)", len(settings.servers))
+	return nil
+}
+
+func (a *ResolvConfApplier) unmanagedLines() ([]string, error) {
+	if !a.existed {
+		return nil, nil
+	}
+	data, err := os.ReadFile(a.backupPath)
+	if err != nil {
+		a.logger.WithFields(log.Fields{
+			"err": err,
+		}).Errorf("Failed to read %s", a.backupPath)
+		return nil, fmt.Errorf("failed to read %s: %s", a.backupPath, err)
+	}
+	lines := make([]string, 0)
+	if len(data) == 0 {
+		return lines, nil
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			switch fields[0] {
+			case "nameserver", "search", "domain":
+				continue
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
@@ -0,0 +1,114 @@
+package spider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func testDNSConfig(servers, domains []string) *NetworkConfigurationConfig {
+	return &NetworkConfigurationConfig{
+		Method:            "static",
+		DomainNameServers: &servers,
+		DomainNames:       &domains,
+	}
+}
+
+func inode(t *testing.T, path string) uint64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Sys().(*syscall.Stat_t).Ino
+}
+
+func TestResolvConfApplier(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "resolv.conf")
+	original := "# managed by hand\nnameserver 9.9.9.9\nsearch old.example\noptions edns0 trust-ad\nsortlist 10.0.0.0/255.0.0.0\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	originalInode := inode(t, path)
+	device := dbus.ObjectPath("/net/connman/iwd/0/3")
+	a := NewResolvConfApplier(path)
+
+	if err := a.ApplyDNS(device, AddressFamilyIPv4, testDNSConfig([]string{"192.168.1.1"}, []string{"home.example"})); err != nil {
+		t.Fatalf("ApplyDNS failed: %s", err)
+	}
+	if err := a.ApplyDNS(device, AddressFamilyIPv6, testDNSConfig([]string{"fd00::1"}, []string{"home.example", "v6.example"})); err != nil {
+		t.Fatalf("ApplyDNS failed: %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Generated by spider\n" +
+		"search home.example v6.example\n" +
+		"nameserver 192.168.1.1\n" +
+		"nameserver fd00::1\n" +
+		"# managed by hand\n" +
+		"options edns0 trust-ad\n" +
+		"sortlist 10.0.0.0/255.0.0.0\n"
+	if string(data) != expected {
+		t.Errorf("resolv.conf =\n%s\nwant\n%s", data, expected)
+	}
+	if inode(t, path) == originalInode {
+		t.Errorf("resolv.conf was modified in place instead of atomically replaced")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "resolv.conf" && entry.Name() != "resolv.conf"+resolvConfBackupSuffix {
+			t.Errorf("unexpected file %s left in %s", entry.Name(), dir)
+		}
+	}
+
+	if err = a.RevertDNS(device, AddressFamilyIPv6); err != nil {
+		t.Fatalf("RevertDNS failed: %s", err)
+	}
+	if data, _ = os.ReadFile(path); strings.Contains(string(data), "fd00::1") {
+		t.Errorf("reverted IPv6 name server still present:\n%s", data)
+	}
+	if err = a.RevertDNS(device, AddressFamilyIPv4); err != nil {
+		t.Fatalf("RevertDNS failed: %s", err)
+	}
+	if data, _ = os.ReadFile(path); string(data) != original {
+		t.Errorf("restored resolv.conf =\n%s\nwant\n%s", data, original)
+	}
+	if inode(t, path) != originalInode {
+		t.Errorf("restored resolv.conf is not the original file")
+	}
+	if _, err = os.Lstat(path + resolvConfBackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("backup was not removed after restore: %v", err)
+	}
+}
+
+func TestResolvConfApplierMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	device := dbus.ObjectPath("/net/connman/iwd/0/3")
+	a := NewResolvConfApplier(path)
+	if err := a.ApplyDNS(device, AddressFamilyIPv4, testDNSConfig([]string{"192.168.1.1"}, nil)); err != nil {
+		t.Fatalf("ApplyDNS failed: %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "# Generated by spider\nnameserver 192.168.1.1\n"; string(data) != expected {
+		t.Errorf("resolv.conf =\n%s\nwant\n%s", data, expected)
+	}
+	if err = a.RevertDNS(device, AddressFamilyIPv4); err != nil {
+		t.Fatalf("RevertDNS failed: %s", err)
+	}
+	if _, err = os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("resolv.conf was not removed after revert: %v", err)
+	}
+}
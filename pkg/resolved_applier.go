@@ -0,0 +1,150 @@
+package spider
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ResolvedService                   = "org.freedesktop.resolve1"
+	ResolvedPath                      = "/org/freedesktop/resolve1"
+	resolvedManagerInterface          = "org.freedesktop.resolve1.Manager"
+	resolvedMethodSetLinkDNS          = resolvedManagerInterface + ".SetLinkDNS"
+	resolvedMethodSetLinkDomains      = resolvedManagerInterface + ".SetLinkDomains"
+	resolvedMethodSetLinkMulticastDNS = resolvedManagerInterface + ".SetLinkMulticastDNS"
+	resolvedMethodRevertLink          = resolvedManagerInterface + ".RevertLink"
+)
+
+type resolvedAddress struct {
+	Family  int32
+	Address []byte
+}
+
+type resolvedDomain struct {
+	Domain    string
+	RouteOnly bool
+}
+
+type ResolvedApplier struct {
+	mu      sync.Mutex
+	conn    *dbus.Conn
+	obj     dbus.BusObject
+	devices map[dbus.ObjectPath]map[AddressFamily]*dnsSettings
+	logger  *log.Entry
+}
+
+func NewResolvedApplier(conn *dbus.Conn) *ResolvedApplier {
+	return &ResolvedApplier{
+		conn:    conn,
+		obj:     conn.Object(ResolvedService, ResolvedPath),
+		devices: make(map[dbus.ObjectPath]map[AddressFamily]*dnsSettings),
+		logger: log.WithFields(log.Fields{
+			"type": "ResolvedApplier",
+			"path": ResolvedPath,
+		}),
+	}
+}
+
+func (a *ResolvedApplier) ApplyDNS(device dbus.ObjectPath, family AddressFamily, config *NetworkConfigurationConfig) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ifindex, err := a.interfaceIndex(device)
+	if err != nil {
+		return err
+	}
+	if _, ok := a.devices[device]; !ok {
+		a.devices[device] = make(map[AddressFamily]*dnsSettings)
+	}
+	a.devices[device][family] = newDNSSettings(config)
+	return a.setLink(ifindex, mergeDNSSettings(orderedDNSSettings(a.devices[device])...))
+}
+
+func (a *ResolvedApplier) RevertDNS(device dbus.ObjectPath, family AddressFamily) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	families, ok := a.devices[device]
+	if !ok {
+		return nil
+	}
+	ifindex, err := a.interfaceIndex(device)
+	if err != nil {
+		return err
+	}
+	delete(families, family)
+	if len(families) > 0 {
+		return a.setLink(ifindex, mergeDNSSettings(orderedDNSSettings(families)...))
+	}
+	delete(a.devices, device)
+	if err = a.obj.Call(resolvedMethodRevertLink, 0, ifindex).Err; err != nil {
+		a.logger.WithFields(log.Fields{
+			"err": err,
+		}).Errorf("Failed to revert configuration of link %d", ifindex)
+		return err
+	}
+	a.logger.Debugf("Reverted configuration of link %d", ifindex)
+	return nil
+}
+
+func (a *ResolvedApplier) setLink(ifindex int32, settings *dnsSettings) error {
+	addresses := make([]resolvedAddress, 0, len(settings.servers))
+	for _, server := range settings.servers {
+		ip := net.ParseIP(server)
+		if ip == nil {
+			return fmt.Errorf("invalid name server address %s", server)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			addresses = append(addresses, resolvedAddress{Family: syscall.AF_INET, Address: ip4})
+		} else {
+			addresses = append(addresses, resolvedAddress{Family: syscall.AF_INET6, Address: ip.To16()})
+		}
+	}
+	domains := make([]resolvedDomain, 0, len(settings.domains))
+	for _, domain := range settings.domains {
+		domains = append(domains, resolvedDomain{Domain: domain})
+	}
+	if err := a.obj.Call(resolvedMethodSetLinkDNS, 0, ifindex, addresses).Err; err != nil {
+		a.logger.WithFields(log.Fields{
+			"err": err,
+		}).Errorf("Failed to set DNS servers of link %d", ifindex)
+		return err
+	}
+	if err := a.obj.Call(resolvedMethodSetLinkDomains, 0, ifindex, domains).Err; err != nil {
+		a.logger.WithFields(log.Fields{
+			"err": err,
+		}).Errorf("Failed to set DNS domains of link %d", ifindex)
+		return err
+	}
+	if settings.mdns != nil {
+		if err := a.obj.Call(resolvedMethodSetLinkMulticastDNS, 0, ifindex, *settings.mdns).Err; err != nil {
+			a.logger.WithFields(log.Fields{
+				"err": err,
+			}).Errorf("Failed to set multicast DNS of link %d", ifindex)
+			return err
+		}
+	}
+	a.logger.Debugf("Configured link %d with %d name server(s) and %d domain(s)", ifindex, len(addresses), len(domains))
+	return nil
+}
+
+func (a *ResolvedApplier) interfaceIndex(device dbus.ObjectPath) (int32, error) {
+	var name string
+	if err := a.conn.Object(IwdService, device).StoreProperty(devicePropertyName, &name); err != nil {
+		a.logger.WithFields(log.Fields{
+			"err": err,
+		}).Errorf("Failed to get Name of Device %s", device)
+		return 0, fmt.Errorf("failed to get Name of Device %s: %s", device, err)
+	}
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		a.logger.WithFields(log.Fields{
+			"err": err,
+		}).Errorf("Failed to find interface %s", name)
+		return 0, fmt.Errorf("failed to find interface %s: %s", name, err)
+	}
+	return int32(iface.Index), nil
+}
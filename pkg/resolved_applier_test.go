@@ -0,0 +1,127 @@
+package spider
+
+import (
+	"net"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+type resolvedCall struct {
+	method  string
+	ifindex int32
+	args    interface{}
+}
+
+type fakeResolved struct {
+	mu    sync.Mutex
+	calls []resolvedCall
+}
+
+func (r *fakeResolved) record(method string, ifindex int32, args interface{}) *dbus.Error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, resolvedCall{method: method, ifindex: ifindex, args: args})
+	return nil
+}
+
+func (r *fakeResolved) takeCalls() []resolvedCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	calls := r.calls
+	r.calls = nil
+	return calls
+}
+
+func TestResolvedApplier(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skipf("no loopback interface: %s", err)
+	}
+	ifindex := int32(lo.Index)
+	client, server := newTestConnPair(t)
+	device := dbus.ObjectPath("/net/connman/iwd/0/3")
+	exportTestObjects(t, server, testObjects{
+		device: {
+			deviceInterface: {
+				"Name": "lo",
+			},
+		},
+	})
+	resolved := &fakeResolved{}
+	if err = server.ExportMethodTable(map[string]interface{}{
+		"SetLinkDNS": func(ifindex int32, addresses []resolvedAddress) *dbus.Error {
+			return resolved.record("SetLinkDNS", ifindex, addresses)
+		},
+		"SetLinkDomains": func(ifindex int32, domains []resolvedDomain) *dbus.Error {
+			return resolved.record("SetLinkDomains", ifindex, domains)
+		},
+		"SetLinkMulticastDNS": func(ifindex int32, mode string) *dbus.Error {
+			return resolved.record("SetLinkMulticastDNS", ifindex, mode)
+		},
+		"RevertLink": func(ifindex int32) *dbus.Error {
+			return resolved.record("RevertLink", ifindex, nil)
+		},
+	}, ResolvedPath, resolvedManagerInterface); err != nil {
+		t.Fatal(err)
+	}
+	a := NewResolvedApplier(client)
+
+	ipv4 := testDNSConfig([]string{"192.168.1.1", "192.168.1.2"}, []string{"home.example"})
+	mdns := "resolve"
+	ipv4.MDNS = &mdns
+	if err = a.ApplyDNS(device, AddressFamilyIPv4, ipv4); err != nil {
+		t.Fatalf("ApplyDNS failed: %s", err)
+	}
+	expected := []resolvedCall{
+		{method: "SetLinkDNS", ifindex: ifindex, args: []resolvedAddress{
+			{Family: syscall.AF_INET, Address: []byte{192, 168, 1, 1}},
+			{Family: syscall.AF_INET, Address: []byte{192, 168, 1, 2}},
+		}},
+		{method: "SetLinkDomains", ifindex: ifindex, args: []resolvedDomain{{Domain: "home.example"}}},
+		{method: "SetLinkMulticastDNS", ifindex: ifindex, args: "resolve"},
+	}
+	if calls := resolved.takeCalls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("calls = %+v; want %+v", calls, expected)
+	}
+
+	if err = a.ApplyDNS(device, AddressFamilyIPv6, testDNSConfig([]string{"fd00::1"}, []string{"v6.example"})); err != nil {
+		t.Fatalf("ApplyDNS failed: %s", err)
+	}
+	expected = []resolvedCall{
+		{method: "SetLinkDNS", ifindex: ifindex, args: []resolvedAddress{
+			{Family: syscall.AF_INET, Address: []byte{192, 168, 1, 1}},
+			{Family: syscall.AF_INET, Address: []byte{192, 168, 1, 2}},
+			{Family: syscall.AF_INET6, Address: net.ParseIP("fd00::1").To16()},
+		}},
+		{method: "SetLinkDomains", ifindex: ifindex, args: []resolvedDomain{{Domain: "home.example"}, {Domain: "v6.example"}}},
+		{method: "SetLinkMulticastDNS", ifindex: ifindex, args: "resolve"},
+	}
+	if calls := resolved.takeCalls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("calls = %+v; want %+v", calls, expected)
+	}
+
+	if err = a.RevertDNS(device, AddressFamilyIPv4); err != nil {
+		t.Fatalf("RevertDNS failed: %s", err)
+	}
+	expected = []resolvedCall{
+		{method: "SetLinkDNS", ifindex: ifindex, args: []resolvedAddress{
+			{Family: syscall.AF_INET6, Address: net.ParseIP("fd00::1").To16()},
+		}},
+		{method: "SetLinkDomains", ifindex: ifindex, args: []resolvedDomain{{Domain: "v6.example"}}},
+	}
+	if calls := resolved.takeCalls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("calls = %+v; want %+v", calls, expected)
+	}
+
+	if err = a.RevertDNS(device, AddressFamilyIPv6); err != nil {
+		t.Fatalf("RevertDNS failed: %s", err)
+	}
+	expected = []resolvedCall{{method: "RevertLink", ifindex: ifindex}}
+	if calls := resolved.takeCalls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("calls = %+v; want %+v", calls, expected)
+	}
+}
@@ -0,0 +1,99 @@
+package spider
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	scopedAgentPath = "/spider/scopedagent"
+)
+
+type scopedAgent struct {
+	conn        *dbus.Conn
+	path        dbus.ObjectPath
+	network     dbus.ObjectPath
+	credentials Credentials
+	logger      *log.Entry
+}
+
+func newScopedAgent(conn *dbus.Conn, network dbus.ObjectPath, credentials Credentials) *scopedAgent {
+	return &scopedAgent{
+		conn:        conn,
+		path:        scopedAgentPath,
+		network:     network,
+		credentials: credentials,
+		logger: log.WithFields(log.Fields{
+			"type":    "ScopedAgent",
+			"network": network,
+		}),
+	}
+}
+
+func (a *scopedAgent) GetConnection() *dbus.Conn {
+	return a.conn
+}
+
+func (a *scopedAgent) GetPath() dbus.ObjectPath {
+	return a.path
+}
+
+func (a *scopedAgent) Release() *dbus.Error {
+	a.logger.Debugf("Released ScopedAgent")
+	return nil
+}
+
+func (a *scopedAgent) check(network dbus.ObjectPath, value string, what string) *dbus.Error {
+	if network != a.network {
+		a.logger.Warnf("Refusing %s request for Network %s", what, network)
+		return NewAgentCanceledError(fmt.Sprintf("agent only answers for Network %s", a.network))
+	}
+	if value == "" {
+		a.logger.Warnf("No %s available for Network %s", what, network)
+		return NewAgentCanceledError(fmt.Sprintf("no %s available", what))
+	}
+	a.logger.Infof("Providing %s for Network %s", what, network)
+	return nil
+}
+
+func (a *scopedAgent) RequestPassphrase(network dbus.ObjectPath) (string, *dbus.Error) {
+	if err := a.check(network, a.credentials.Passphrase, "passphrase"); err != nil {
+		return "", err
+	}
+	return a.credentials.Passphrase, nil
+}
+
+func (a *scopedAgent) RequestPrivateKeyPassphrase(network dbus.ObjectPath) (string, *dbus.Error) {
+	if err := a.check(network, a.credentials.PrivateKeyPassphrase, "private key passphrase"); err != nil {
+		return "", err
+	}
+	return a.credentials.PrivateKeyPassphrase, nil
+}
+
+func (a *scopedAgent) RequestUserNameAndPassword(network dbus.ObjectPath) (string, string, *dbus.Error) {
+	if err := a.check(network, a.credentials.User, "user name"); err != nil {
+		return "", "", err
+	}
+	if err := a.check(network, a.credentials.Password, "password"); err != nil {
+		return "", "", err
+	}
+	return a.credentials.User, a.credentials.Password, nil
+}
+
+func (a *scopedAgent) RequestUserPassword(network dbus.ObjectPath, user string) (string, *dbus.Error) {
+	if err := a.check(network, a.credentials.Password, "user password"); err != nil {
+		return "", err
+	}
+	if a.credentials.User != "" && a.credentials.User != user {
+		a.logger.Warnf("Refusing password request for unexpected user %s", user)
+		return "", NewAgentCanceledError(fmt.Sprintf("no password available for user %s", user))
+	}
+	return a.credentials.Password, nil
+}
+
+func (a *scopedAgent) Cancel(reason string) *dbus.Error {
+	a.logger.Infof("Canceling request because: %s", reason)
+	return nil
+}
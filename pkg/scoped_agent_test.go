@@ -0,0 +1,43 @@
+package spider
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestScopedAgentRejectsOtherNetworks(t *testing.T) {
+	client, server := newTestConnPair(t)
+	exportTestAgentManager(t, server, nil)
+	credentials := Credentials{Passphrase: "password", User: "user", Password: "secret"}
+	s, _ := serveTestAgent(t, client, newScopedAgent(client, testNetworkPath, credentials))
+	other := dbus.ObjectPath("/net/connman/iwd/0/3/4f6666696365_psk")
+	for _, call := range []struct {
+		method string
+		args   []interface{}
+	}{
+		{method: "RequestPassphrase", args: []interface{}{other}},
+		{method: "RequestPrivateKeyPassphrase", args: []interface{}{other}},
+		{method: "RequestUserNameAndPassword", args: []interface{}{other}},
+		{method: "RequestUserPassword", args: []interface{}{other, "user"}},
+	} {
+		result := callTestAs(t, server, testIwdOwner, s.GetPath(), AgentInterface+"."+call.method, call.args...)
+		assertTestDBusError(t, call.method+" for another network", result.Err, AgentErrorCanceled)
+		for _, value := range result.Body {
+			if value == "password" || value == "secret" {
+				t.Errorf("%s for another network leaked credentials: %v", call.method, result.Body)
+			}
+		}
+	}
+	var user, password string
+	if err := callTestAs(t, server, testIwdOwner, s.GetPath(), AgentInterface+".RequestUserNameAndPassword", testNetworkPath).Store(&user, &password); err != nil {
+		t.Fatalf("RequestUserNameAndPassword for the scoped network failed: %s", err)
+	}
+	if user != "user" || password != "secret" {
+		t.Errorf("RequestUserNameAndPassword = %q, %q; want user, secret", user, password)
+	}
+	result := callTestAs(t, server, testIwdOwner, s.GetPath(), AgentInterface+".RequestUserPassword", testNetworkPath, "intruder")
+	assertTestDBusError(t, "RequestUserPassword for another user", result.Err, AgentErrorCanceled)
+	result = callTestAs(t, server, testIwdOwner, s.GetPath(), AgentInterface+".RequestPrivateKeyPassphrase", testNetworkPath)
+	assertTestDBusError(t, "RequestPrivateKeyPassphrase without one", result.Err, AgentErrorCanceled)
+}
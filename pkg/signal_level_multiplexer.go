@@ -0,0 +1,254 @@
+package spider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	SignalLevelMultiplexerPath = "/spider/signallevelmultiplexer"
+)
+
+type SignalLevelCallback func(device dbus.ObjectPath, level uint8)
+
+type signalLevelSubscriber struct {
+	levels    []int16
+	callback  SignalLevelCallback
+	lastLevel map[dbus.ObjectPath]uint8
+}
+
+type SignalLevelMultiplexer struct {
+	mu          sync.Mutex
+	conn        *dbus.Conn
+	station     *Station
+	nextID      uint64
+	subscribers map[uint64]*signalLevelSubscriber
+	union       []int16
+	server      *AgentServer
+	stopWatch   func()
+	logger      *log.Entry
+}
+
+type signalLevelMultiplexerAgent struct {
+	m *SignalLevelMultiplexer
+}
+
+func NewSignalLevelMultiplexer(conn *dbus.Conn, station *Station) *SignalLevelMultiplexer {
+	log.SetReportCaller(true)
+	return &SignalLevelMultiplexer{
+		conn:        conn,
+		station:     station,
+		subscribers: make(map[uint64]*signalLevelSubscriber),
+		logger: log.WithFields(log.Fields{
+			"type":    "SignalLevelMultiplexer",
+			"station": station.GetPath(),
+		}),
+	}
+}
+
+func (m *SignalLevelMultiplexer) Subscribe(levels []int16, callback SignalLevelCallback) (func(), error) {
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("at least one signal level threshold is required")
+	}
+	if callback == nil {
+		return nil, fmt.Errorf("callback must not be nil")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	id := m.nextID
+	m.subscribers[id] = &signalLevelSubscriber{
+		levels:    sortSignalLevels(levels),
+		callback:  callback,
+		lastLevel: make(map[dbus.ObjectPath]uint8),
+	}
+	if err := m.reregister(); err != nil {
+		delete(m.subscribers, id)
+		_ = m.reregister()
+		return nil, err
+	}
+	m.logger.Debugf("Added subscriber %d with levels %v", id, m.subscribers[id].levels)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.unsubscribe(id)
+		})
+	}, nil
+}
+
+func (m *SignalLevelMultiplexer) GetLevels() []int16 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.union)
+}
+
+func (m *SignalLevelMultiplexer) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = make(map[uint64]*signalLevelSubscriber)
+	m.stopReregistrationWatch()
+	return m.reregister()
+}
+
+func (m *SignalLevelMultiplexer) unsubscribe(id uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.subscribers[id]; !ok {
+		return
+	}
+	delete(m.subscribers, id)
+	m.logger.Debugf("Removed subscriber %d", id)
+	if err := m.reregister(); err != nil {
+		m.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("failed to re-register SignalLevelAgent")
+	}
+}
+
+func (m *SignalLevelMultiplexer) reregister() error {
+	union := make([]int16, 0)
+	for _, s := range m.subscribers {
+		union = append(union, s.levels...)
+	}
+	union = sortSignalLevels(union)
+	if m.server != nil {
+		select {
+		case <-m.server.Done():
+			m.server = nil
+		default:
+		}
+	}
+	if m.server != nil && slices.Equal(union, m.union) {
+		return nil
+	}
+	if m.server != nil {
+		if err := m.server.Close(); err != nil {
+			m.logger.WithFields(log.Fields{
+				"err": err,
+			}).Warn("failed to unregister previous SignalLevelAgent")
+		}
+		m.server = nil
+	}
+	m.union = union
+	if len(union) == 0 {
+		return nil
+	}
+	server, err := ServeSignalLevelAgent(context.Background(), m.conn, m.station, &signalLevelMultiplexerAgent{m: m}, union)
+	if err != nil {
+		return err
+	}
+	m.server = server
+	m.logger.Debugf("Registered SignalLevelAgent with levels %v", union)
+	return nil
+}
+
+func (m *SignalLevelMultiplexer) released() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.server = nil
+	if len(m.subscribers) == 0 || m.stopWatch != nil {
+		return
+	}
+	changes, stop, err := watchPropertiesChanged(m.conn, m.station.GetPath(), stationInterface)
+	if err != nil {
+		m.logger.WithFields(log.Fields{
+			"err": err,
+		}).Error("failed to watch Station state to re-register SignalLevelAgent")
+		return
+	}
+	m.stopWatch = stop
+	m.logger.Debugf("Re-registering SignalLevelAgent on the next Station state change")
+	go func() {
+		for change := range changes {
+			if _, ok := change.changed["State"]; !ok {
+				continue
+			}
+			m.mu.Lock()
+			if err2 := m.reregister(); err2 != nil {
+				m.logger.WithFields(log.Fields{
+					"err": err2,
+				}).Warn("failed to re-register released SignalLevelAgent")
+			} else {
+				m.stopReregistrationWatch()
+			}
+			m.mu.Unlock()
+		}
+	}()
+}
+
+func (m *SignalLevelMultiplexer) stopReregistrationWatch() {
+	if m.stopWatch != nil {
+		m.stopWatch()
+		m.stopWatch = nil
+	}
+}
+
+func (m *SignalLevelMultiplexer) dispatch(device dbus.ObjectPath, level uint8) {
+	m.mu.Lock()
+	union := m.union
+	subscribers := make([]*signalLevelSubscriber, 0, len(m.subscribers))
+	for _, s := range m.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	m.mu.Unlock()
+	if int(level) > len(union) {
+		m.logger.Warnf("Ignoring out of range signal level %d for Device %s", level, device)
+		return
+	}
+	for _, s := range subscribers {
+		mapped := mapSignalLevel(union, s.levels, level)
+		m.mu.Lock()
+		last, ok := s.lastLevel[device]
+		s.lastLevel[device] = mapped
+		m.mu.Unlock()
+		if ok && last == mapped {
+			continue
+		}
+		s.callback(device, mapped)
+	}
+}
+
+func (a *signalLevelMultiplexerAgent) GetConnection() *dbus.Conn {
+	return a.m.conn
+}
+
+func (a *signalLevelMultiplexerAgent) GetPath() dbus.ObjectPath {
+	return SignalLevelMultiplexerPath
+}
+
+func (a *signalLevelMultiplexerAgent) Release(device dbus.ObjectPath) *dbus.Error {
+	a.m.logger.Infof("SignalLevelAgent released for Device %s", device)
+	a.m.released()
+	return nil
+}
+
+func (a *signalLevelMultiplexerAgent) Changed(device dbus.ObjectPath, level uint8) *dbus.Error {
+	a.m.dispatch(device, level)
+	return nil
+}
+
+func mapSignalLevel(union, levels []int16, level uint8) uint8 {
+	if level == 0 {
+		return 0
+	}
+	floor := union[level-1]
+	var mapped uint8
+	for _, threshold := range levels {
+		if threshold >= floor {
+			mapped++
+		}
+	}
+	return mapped
+}
+
+func sortSignalLevels(levels []int16) []int16 {
+	sorted := slices.Clone(levels)
+	slices.Sort(sorted)
+	slices.Reverse(sorted)
+	return slices.Compact(sorted)
+}
@@ -0,0 +1,165 @@
+package spider
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+type testSignalLevelRegistration struct {
+	path   dbus.ObjectPath
+	levels []int16
+}
+
+type testSignalLevelStation struct {
+	mu            sync.Mutex
+	registrations []testSignalLevelRegistration
+}
+
+func exportTestSignalLevelStation(t *testing.T, conn *dbus.Conn) *testSignalLevelStation {
+	t.Helper()
+	bus := exportTestBus(t, conn)
+	bus.setOwner(t, IwdService, testIwdOwner)
+	station := &testSignalLevelStation{}
+	if err := conn.ExportMethodTable(map[string]interface{}{
+		"RegisterSignalLevelAgent": func(path dbus.ObjectPath, levels []int16) *dbus.Error {
+			station.mu.Lock()
+			defer station.mu.Unlock()
+			station.registrations = append(station.registrations, testSignalLevelRegistration{path: path, levels: levels})
+			return nil
+		},
+		"UnregisterSignalLevelAgent": func(path dbus.ObjectPath) *dbus.Error {
+			return nil
+		},
+	}, testDevicePath, stationInterface); err != nil {
+		t.Fatal(err)
+	}
+	return station
+}
+
+func (s *testSignalLevelStation) getRegistrations() []testSignalLevelRegistration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.registrations)
+}
+
+func testSignalLevelForRSSI(levels []int16, rssi int16) uint8 {
+	var level uint8
+	for _, threshold := range levels {
+		if threshold > rssi {
+			level++
+		}
+	}
+	return level
+}
+
+func TestMapSignalLevel(t *testing.T) {
+	tests := []struct {
+		name        string
+		subscribers [][]int16
+	}{
+		{name: "disjoint", subscribers: [][]int16{{-60, -80}, {-50, -70}}},
+		{name: "shared thresholds", subscribers: [][]int16{{-60, -70, -80}, {-70}}},
+		{name: "single subscriber", subscribers: [][]int16{{-80, -40, -60}}},
+		{name: "nested", subscribers: [][]int16{{-65}, {-50, -60, -65, -70, -90}, {-90, -50}}},
+	}
+	for _, tt := range tests {
+		union := make([]int16, 0)
+		for _, levels := range tt.subscribers {
+			union = append(union, levels...)
+		}
+		union = sortSignalLevels(union)
+		for rssi := int16(-100); rssi <= -30; rssi++ {
+			level := testSignalLevelForRSSI(union, rssi)
+			for _, levels := range tt.subscribers {
+				sorted := sortSignalLevels(levels)
+				expected := testSignalLevelForRSSI(sorted, rssi)
+				if mapped := mapSignalLevel(union, sorted, level); mapped != expected {
+					t.Errorf("%s: RSSI %d is level %d of %v; mapped to %d of %v, want %d", tt.name, rssi, level, union, mapped, sorted, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestMapSignalLevelTable(t *testing.T) {
+	union := []int16{-50, -60, -70, -80}
+	tests := []struct {
+		levels   []int16
+		expected []uint8
+	}{
+		{levels: []int16{-60, -80}, expected: []uint8{0, 0, 1, 1, 2}},
+		{levels: []int16{-50, -70}, expected: []uint8{0, 1, 1, 2, 2}},
+		{levels: []int16{-50, -60, -70, -80}, expected: []uint8{0, 1, 2, 3, 4}},
+		{levels: []int16{-80}, expected: []uint8{0, 0, 0, 0, 1}},
+	}
+	for _, tt := range tests {
+		for level, expected := range tt.expected {
+			if mapped := mapSignalLevel(union, tt.levels, uint8(level)); mapped != expected {
+				t.Errorf("mapSignalLevel(%v, %v, %d) = %d; want %d", union, tt.levels, level, mapped, expected)
+			}
+		}
+	}
+}
+
+func TestSignalLevelMultiplexerReregistersAfterRelease(t *testing.T) {
+	client, server := newTestConnPair(t)
+	fake := exportTestSignalLevelStation(t, server)
+	station, err := NewStation(client, testDevicePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewSignalLevelMultiplexer(client, station)
+	t.Cleanup(func() {
+		_ = m.Close()
+	})
+	var mu sync.Mutex
+	levels := make([]uint8, 0)
+	unsubscribe, err := m.Subscribe([]int16{-60, -80}, func(device dbus.ObjectPath, level uint8) {
+		mu.Lock()
+		defer mu.Unlock()
+		levels = append(levels, level)
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %s", err)
+	}
+	defer unsubscribe()
+	if _, err = m.Subscribe([]int16{-70}, func(dbus.ObjectPath, uint8) {}); err != nil {
+		t.Fatalf("second Subscribe failed: %s", err)
+	}
+	registrations := fake.getRegistrations()
+	if len(registrations) != 2 || !slices.Equal(registrations[1].levels, []int16{-60, -70, -80}) {
+		t.Fatalf("registrations = %v; want the union of both subscribers last", registrations)
+	}
+	path := registrations[1].path
+	if call := callTestAs(t, server, testIwdOwner, path, SignalLevelAgentInterface+".Changed", testDevicePath, uint8(2)); call.Err != nil {
+		t.Fatalf("Changed failed: %s", call.Err)
+	}
+
+	if call := callTestAs(t, server, testIwdOwner, path, SignalLevelAgentInterface+".Release", testDevicePath); call.Err != nil {
+		t.Fatalf("Release failed: %s", call.Err)
+	}
+	emitTestPropertiesChanged(t, server, testDevicePath, stationInterface, map[string]dbus.Variant{
+		"Scanning": dbus.MakeVariant(true),
+	}, nil)
+	emitTestPropertiesChanged(t, server, testDevicePath, stationInterface, map[string]dbus.Variant{
+		"State": dbus.MakeVariant("connected"),
+	}, nil)
+	waitForTestCondition(t, "SignalLevelAgent to re-register", func() bool {
+		return len(fake.getRegistrations()) == 3
+	})
+	registrations = fake.getRegistrations()
+	if !slices.Equal(registrations[2].levels, []int16{-60, -70, -80}) || registrations[2].path == path {
+		t.Errorf("re-registration = %v; want the union at a new path", registrations[2])
+	}
+	if call := callTestAs(t, server, testIwdOwner, registrations[2].path, SignalLevelAgentInterface+".Changed", testDevicePath, uint8(3)); call.Err != nil {
+		t.Fatalf("Changed after re-registration failed: %s", call.Err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(levels, []uint8{1, 2}) {
+		t.Errorf("subscriber levels = %v; want [1 2]", levels)
+	}
+}
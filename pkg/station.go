@@ -1,6 +1,7 @@
 package spider
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/godbus/dbus/v5"
@@ -20,6 +21,8 @@ const (
 	stationMethodConnectHiddenNetwork       = stationInterface + ".ConnectHiddenNetwork"
 	stationMethodRegisterSignalLevelAgent   = stationInterface + ".RegisterSignalLevelAgent"
 	stationMethodUnregisterSignalLevelAgent = stationInterface + ".UnregisterSignalLevelAgent"
+	stationDebugInterface                   = "net.connman.iwd.StationDebug"
+	stationDebugMethodGetNetworks           = stationDebugInterface + ".GetNetworks"
 )
 
 var (
@@ -40,6 +43,7 @@ type Stationer interface {
 	ConnectHiddenNetwork(ssid string) error
 	RegisterSignalLevelAgent(client SignalLevelAgentClient, levels []int16) error
 	UnregisterSignalLevelAgent(client SignalLevelAgentClient) error
+	WatchConnectionEvents(ctx context.Context, prober *ConnectivityProber) (<-chan *StationConnectionEvent, error)
 }
 
 type StationOrderedNetwork struct {
@@ -57,6 +61,29 @@ type HiddenAccessPoint struct {
 	Type           string
 }
 
+type StationConnectionEvent struct {
+	Station          dbus.ObjectPath
+	State            string
+	ConnectedNetwork *dbus.ObjectPath
+	Connectivity     *ConnectivityResult
+}
+
+func (e *StationConnectionEvent) String() string {
+	var cn string
+	var connectivity string
+	if e.ConnectedNetwork == nil {
+		cn = "<nil>"
+	} else {
+		cn = string(*e.ConnectedNetwork)
+	}
+	if e.Connectivity == nil {
+		connectivity = "<nil>"
+	} else {
+		connectivity = e.Connectivity.String()
+	}
+	return fmt.Sprintf("{Station: %s, State: %s, ConnectedNetwork: %s, Connectivity: %s}", e.Station, e.State, cn, connectivity)
+}
+
 type Station struct {
 	conn *dbus.Conn
 	obj  dbus.BusObject
@@ -316,3 +343,59 @@ func (s *Station) UnregisterSignalLevelAgent(client SignalLevelAgentClient) erro
 	stationLogger.Debugf("unregistered SignalLevelAgent %s", clientPath)
 	return nil
 }
+
+func (s *Station) WatchConnectionEvents(ctx context.Context, prober *ConnectivityProber) (<-chan *StationConnectionEvent, error) {
+	changes, stop, err := watchPropertiesChanged(s.conn, s.path, stationInterface)
+	if err != nil {
+		stationLogger.WithFields(log.Fields{
+			"err": err,
+		}).Error("Failed to watch for connection events")
+		return nil, err
+	}
+	events := make(chan *StationConnectionEvent)
+	go func() {
+		defer close(events)
+		defer stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case change, ok := <-changes:
+				if !ok {
+					return
+				}
+				variant, ok := change.changed["State"]
+				if !ok {
+					continue
+				}
+				event := &StationConnectionEvent{
+					Station: s.path,
+				}
+				if err2 := variant.Store(&event.State); err2 != nil {
+					stationLogger.WithFields(log.Fields{
+						"err": err2,
+					}).Error("Failed to store changed property 'state'")
+					continue
+				}
+				if event.State == "connected" {
+					if variant2, err2 := s.obj.GetProperty(stationPropertyConnectedNetwork); err2 == nil {
+						var path dbus.ObjectPath
+						if err3 := variant2.Store(&path); err3 == nil {
+							event.ConnectedNetwork = &path
+						}
+					}
+					if prober != nil {
+						event.Connectivity = prober.Probe(ctx)
+					}
+				}
+				stationLogger.Debugf("Connection event %s", event)
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
@@ -8,8 +8,8 @@ import (
 )
 
 const (
-	stationDiagnosticInterface  = "net.connman.iwd.StationDiagnostic"
-	stationMethodGetDiagnostics = stationInterface + "GetDiagnostics"
+	stationDiagnosticInterface            = "net.connman.iwd.StationDiagnostic"
+	stationDiagnosticMethodGetDiagnostics = stationDiagnosticInterface + ".GetDiagnostics"
 )
 
 var (
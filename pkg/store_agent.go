@@ -0,0 +1,122 @@
+package spider
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	StoreAgentPath = "/spider/storeagent"
+)
+
+var (
+	storeAgentLogger *log.Entry
+)
+
+type StoreAgent struct {
+	conn  *dbus.Conn
+	path  dbus.ObjectPath
+	store CredentialStore
+}
+
+func NewStoreAgent(conn *dbus.Conn, store CredentialStore) *StoreAgent {
+	log.SetReportCaller(true)
+	storeAgentLogger = log.WithFields(log.Fields{
+		"type": "StoreAgent",
+		"path": StoreAgentPath,
+	})
+	return &StoreAgent{
+		conn:  conn,
+		path:  StoreAgentPath,
+		store: store,
+	}
+}
+
+func (a *StoreAgent) GetConnection() *dbus.Conn {
+	return a.conn
+}
+
+func (a *StoreAgent) GetPath() dbus.ObjectPath {
+	return a.path
+}
+
+func (a *StoreAgent) Release() *dbus.Error {
+	storeAgentLogger.Infof("Released StoreAgent")
+	return nil
+}
+
+func (a *StoreAgent) lookup(network dbus.ObjectPath) (*Credentials, *dbus.Error) {
+	name, netType, err := getNetworkNameAndType(a.conn, network)
+	if err != nil {
+		storeAgentLogger.WithFields(log.Fields{
+			"err": err,
+		}).Errorf("failed to resolve Network %s", network)
+		return nil, NewAgentCanceledError(fmt.Sprintf("failed to resolve Network %s", network))
+	}
+	credentials, err := a.store.Lookup(name, netType)
+	if err != nil {
+		if !errors.Is(err, ErrCredentialsNotFound) {
+			storeAgentLogger.WithFields(log.Fields{
+				"err": err,
+			}).Errorf("failed to look up credentials for %s (%s)", name, netType)
+		}
+		return nil, NewAgentCanceledError(fmt.Sprintf("no credentials for %s (%s)", name, netType))
+	}
+	return credentials, nil
+}
+
+func (a *StoreAgent) RequestPassphrase(network dbus.ObjectPath) (string, *dbus.Error) {
+	storeAgentLogger.Infof("Requesting passphrase for Network %s", network)
+	credentials, err := a.lookup(network)
+	if err != nil {
+		return "", err
+	}
+	if credentials.Passphrase == "" {
+		return "", NewAgentCanceledError("no passphrase stored")
+	}
+	return credentials.Passphrase, nil
+}
+
+func (a *StoreAgent) RequestPrivateKeyPassphrase(network dbus.ObjectPath) (string, *dbus.Error) {
+	storeAgentLogger.Infof("Requesting private key passphrase for Network %s", network)
+	credentials, err := a.lookup(network)
+	if err != nil {
+		return "", err
+	}
+	if credentials.PrivateKeyPassphrase == "" {
+		return "", NewAgentCanceledError("no private key passphrase stored")
+	}
+	return credentials.PrivateKeyPassphrase, nil
+}
+
+func (a *StoreAgent) RequestUserNameAndPassword(network dbus.ObjectPath) (string, string, *dbus.Error) {
+	storeAgentLogger.Infof("Requesting username and password for Network %s", network)
+	credentials, err := a.lookup(network)
+	if err != nil {
+		return "", "", err
+	}
+	if credentials.User == "" || credentials.Password == "" {
+		return "", "", NewAgentCanceledError("no user name and password stored")
+	}
+	return credentials.User, credentials.Password, nil
+}
+
+func (a *StoreAgent) RequestUserPassword(network dbus.ObjectPath, user string) (string, *dbus.Error) {
+	storeAgentLogger.Infof("Requesting user password for Network %s", network)
+	credentials, err := a.lookup(network)
+	if err != nil {
+		return "", err
+	}
+	if credentials.Password == "" || credentials.User != "" && credentials.User != user {
+		return "", NewAgentCanceledError(fmt.Sprintf("no password stored for user %s", user))
+	}
+	return credentials.Password, nil
+}
+
+func (a *StoreAgent) Cancel(reason string) *dbus.Error {
+	storeAgentLogger.Infof("Canceling request because: %s", reason)
+	return nil
+}
@@ -0,0 +1,258 @@
+package spider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+const (
+	TerminalAgentPath           = "/spider/terminalagent"
+	DefaultTerminalAgentTimeout = 60 * time.Second
+	DefaultTerminalDevice       = "/dev/tty"
+)
+
+var (
+	terminalAgentLogger *log.Entry
+
+	errTerminalPromptAborted = errors.New("prompt aborted by user")
+)
+
+type terminalPrompt struct {
+	label  string
+	secret bool
+}
+
+type TerminalAgent struct {
+	conn    *dbus.Conn
+	path    dbus.ObjectPath
+	device  string
+	timeout time.Duration
+	prompt  sync.Mutex
+	mu      sync.Mutex
+	cancel  context.CancelCauseFunc
+}
+
+func NewTerminalAgent(conn *dbus.Conn) *TerminalAgent {
+	log.SetReportCaller(true)
+	terminalAgentLogger = log.WithFields(log.Fields{
+		"type": "TerminalAgent",
+		"path": TerminalAgentPath,
+	})
+	return &TerminalAgent{
+		conn:    conn,
+		path:    TerminalAgentPath,
+		device:  DefaultTerminalDevice,
+		timeout: DefaultTerminalAgentTimeout,
+	}
+}
+
+func (a *TerminalAgent) SetTimeout(timeout time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.timeout = timeout
+}
+
+func (a *TerminalAgent) SetDevice(device string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.device = device
+}
+
+func (a *TerminalAgent) GetConnection() *dbus.Conn {
+	return a.conn
+}
+
+func (a *TerminalAgent) GetPath() dbus.ObjectPath {
+	return a.path
+}
+
+func (a *TerminalAgent) Release() *dbus.Error {
+	terminalAgentLogger.Infof("Released TerminalAgent")
+	a.abort(fmt.Errorf("agent released"))
+	return nil
+}
+
+func (a *TerminalAgent) RequestPassphrase(network dbus.ObjectPath) (string, *dbus.Error) {
+	terminalAgentLogger.Infof("Requesting passphrase for Network %s", network)
+	values, err := a.ask(network, terminalPrompt{label: "Passphrase", secret: true})
+	if err != nil {
+		return "", err
+	}
+	return values[0], nil
+}
+
+func (a *TerminalAgent) RequestPrivateKeyPassphrase(network dbus.ObjectPath) (string, *dbus.Error) {
+	terminalAgentLogger.Infof("Requesting private key passphrase for Network %s", network)
+	values, err := a.ask(network, terminalPrompt{label: "Private key passphrase", secret: true})
+	if err != nil {
+		return "", err
+	}
+	return values[0], nil
+}
+
+func (a *TerminalAgent) RequestUserNameAndPassword(network dbus.ObjectPath) (string, string, *dbus.Error) {
+	terminalAgentLogger.Infof("Requesting username and password for Network %s", network)
+	values, err := a.ask(network, terminalPrompt{label: "Username"}, terminalPrompt{label: "Password", secret: true})
+	if err != nil {
+		return "", "", err
+	}
+	return values[0], values[1], nil
+}
+
+func (a *TerminalAgent) RequestUserPassword(network dbus.ObjectPath, user string) (string, *dbus.Error) {
+	terminalAgentLogger.Infof("Requesting user password for Network %s", network)
+	values, err := a.ask(network, terminalPrompt{label: fmt.Sprintf("Password for %s", user), secret: true})
+	if err != nil {
+		return "", err
+	}
+	return values[0], nil
+}
+
+func (a *TerminalAgent) Cancel(reason string) *dbus.Error {
+	terminalAgentLogger.Infof("Canceling request because: %s", reason)
+	a.abort(fmt.Errorf("canceled by iwd: %s", reason))
+	return nil
+}
+
+func (a *TerminalAgent) abort(cause error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cancel != nil {
+		a.cancel(cause)
+	}
+}
+
+func (a *TerminalAgent) ask(network dbus.ObjectPath, prompts ...terminalPrompt) ([]string, *dbus.Error) {
+	a.prompt.Lock()
+	defer a.prompt.Unlock()
+
+	a.mu.Lock()
+	device := a.device
+	timeout := a.timeout
+	ctx, cancel := context.WithCancelCause(context.Background())
+	a.cancel = cancel
+	a.mu.Unlock()
+	ctx, cancelTimeout := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("timed out after %s", timeout))
+	defer func() {
+		cancelTimeout()
+		a.mu.Lock()
+		a.cancel = nil
+		a.mu.Unlock()
+		cancel(nil)
+	}()
+
+	name, _, err := getNetworkNameAndType(a.conn, network)
+	if err != nil {
+		terminalAgentLogger.WithFields(log.Fields{
+			"err": err,
+		}).Warnf("failed to resolve Network %s", network)
+		name = string(network)
+	}
+	values, err := promptTerminal(ctx, device, fmt.Sprintf("Network %s requires credentials", name), prompts)
+	if err != nil {
+		terminalAgentLogger.WithFields(log.Fields{
+			"err": err,
+		}).Warnf("Prompt for Network %s failed", network)
+		return nil, NewAgentCanceledError(err.Error())
+	}
+	return values, nil
+}
+
+func promptTerminal(ctx context.Context, device, header string, prompts []terminalPrompt) ([]string, error) {
+	tty, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open terminal %s: %s", device, err)
+	}
+	defer func() {
+		_ = tty.Close()
+	}()
+	rawConn, err := tty.SyscallConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to access terminal %s: %s", device, err)
+	}
+	var state *term.State
+	if err2 := rawConn.Control(func(fd uintptr) {
+		state, err = term.MakeRaw(int(fd))
+	}); err2 != nil {
+		err = err2
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to put terminal %s into raw mode: %s", device, err)
+	}
+	defer func() {
+		_ = rawConn.Control(func(fd uintptr) {
+			_ = term.Restore(int(fd), state)
+		})
+	}()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = tty.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	if _, err = fmt.Fprintf(tty, "\r\n%s\r\n", header); err != nil {
+		return nil, fmt.Errorf("failed to write to terminal %s: %s", device, err)
+	}
+	values := make([]string, 0, len(prompts))
+	for _, p := range prompts {
+		if _, err = fmt.Fprintf(tty, "%s: ", p.label); err != nil {
+			return nil, fmt.Errorf("failed to write to terminal %s: %s", device, err)
+		}
+		value, err2 := readTerminalLine(tty, !p.secret)
+		_, _ = fmt.Fprint(tty, "\r\n")
+		if err2 != nil {
+			if errors.Is(err2, os.ErrDeadlineExceeded) && ctx.Err() != nil {
+				return nil, context.Cause(ctx)
+			}
+			return nil, err2
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func readTerminalLine(tty *os.File, echo bool) (string, error) {
+	line := make([]byte, 0, 64)
+	buf := make([]byte, 1)
+	for {
+		if _, err := tty.Read(buf); err != nil {
+			return "", err
+		}
+		switch c := buf[0]; c {
+		case '\r', '\n':
+			return string(line), nil
+		case 3, 4:
+			return "", errTerminalPromptAborted
+		case 8, 127:
+			if len(line) > 0 {
+				_, size := utf8.DecodeLastRune(line)
+				line = line[:len(line)-size]
+				if echo {
+					_, _ = tty.Write([]byte("\b \b"))
+				}
+			}
+		default:
+			if c < 32 {
+				continue
+			}
+			line = append(line, c)
+			if echo {
+				_, _ = tty.Write(buf)
+			}
+		}
+	}
+}
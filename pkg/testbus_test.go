@@ -0,0 +1,251 @@
+package spider
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+type testObjects map[dbus.ObjectPath]map[string]map[string]interface{}
+
+func newTestConnPair(t *testing.T) (*dbus.Conn, *dbus.Conn) {
+	t.Helper()
+	clientEnd, clientRelay := net.Pipe()
+	serverEnd, serverRelay := net.Pipe()
+	go relayTestConn(clientRelay, serverRelay)
+	conns := make([]*dbus.Conn, 0, 2)
+	for _, end := range []net.Conn{clientEnd, serverEnd} {
+		conn, err := dbus.NewConn(end)
+		if err != nil {
+			t.Fatalf("failed to create test connection: %s", err)
+		}
+		if err = conn.Auth([]dbus.Auth{dbus.AuthExternal("0")}); err != nil {
+			t.Fatalf("failed to authenticate test connection: %s", err)
+		}
+		t.Cleanup(func() {
+			_ = conn.Close()
+		})
+		conns = append(conns, conn)
+	}
+	return conns[0], conns[1]
+}
+
+func relayTestConn(a, b net.Conn) {
+	defer a.Close()
+	defer b.Close()
+	readers := make([]io.Reader, 2)
+	var wg sync.WaitGroup
+	for i, c := range []net.Conn{a, b} {
+		wg.Add(1)
+		go func(i int, c net.Conn) {
+			defer wg.Done()
+			if r, err := serveTestAuth(c); err == nil {
+				readers[i] = r
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	ra, rb := readers[0], readers[1]
+	if ra == nil || rb == nil {
+		return
+	}
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(b, ra)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(a, rb)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+func serveTestAuth(c net.Conn) (io.Reader, error) {
+	r := bufio.NewReader(c)
+	if b, err := r.ReadByte(); err != nil || b != 0 {
+		return nil, fmt.Errorf("expected NUL byte")
+	}
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		var reply string
+		switch {
+		case bytes.Equal(line, []byte("AUTH")):
+			reply = "REJECTED EXTERNAL"
+		case bytes.HasPrefix(line, []byte("AUTH EXTERNAL")):
+			reply = "OK 0123456789abcdef0123456789abcdef"
+		case bytes.Equal(line, []byte("BEGIN")):
+			return r, nil
+		default:
+			reply = "ERROR"
+		}
+		if _, err = fmt.Fprintf(c, "%s\r\n", reply); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func exportTestObjects(t *testing.T, conn *dbus.Conn, objects testObjects) map[dbus.ObjectPath]*prop.Properties {
+	t.Helper()
+	exported := make(map[dbus.ObjectPath]*prop.Properties, len(objects))
+	for path, ifaces := range objects {
+		propMap := make(prop.Map, len(ifaces))
+		for iface, props := range ifaces {
+			propMap[iface] = make(map[string]*prop.Prop, len(props))
+			for name, value := range props {
+				propMap[iface][name] = &prop.Prop{Value: value, Writable: true, Emit: prop.EmitFalse}
+			}
+		}
+		props, err := prop.Export(conn, path, propMap)
+		if err != nil {
+			t.Fatalf("failed to export properties for %s: %s", path, err)
+		}
+		exported[path] = props
+	}
+	getManagedObjects := func() (map[dbus.ObjectPath]map[string]map[string]dbus.Variant, *dbus.Error) {
+		managed := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant, len(objects))
+		for path, ifaces := range objects {
+			managed[path] = make(map[string]map[string]dbus.Variant, len(ifaces))
+			for iface := range ifaces {
+				managed[path][iface], _ = exported[path].GetAll(iface)
+			}
+		}
+		return managed, nil
+	}
+	if err := conn.ExportMethodTable(map[string]interface{}{
+		"GetManagedObjects": getManagedObjects,
+	}, "/", "org.freedesktop.DBus.ObjectManager"); err != nil {
+		t.Fatalf("failed to export ObjectManager: %s", err)
+	}
+	return exported
+}
+
+const (
+	testBusPath      = "/org/freedesktop/DBus"
+	testBusInterface = "org.freedesktop.DBus"
+)
+
+type testBus struct {
+	conn   *dbus.Conn
+	mu     sync.Mutex
+	owners map[string]string
+}
+
+func exportTestBus(t *testing.T, conn *dbus.Conn) *testBus {
+	t.Helper()
+	b := &testBus{
+		conn:   conn,
+		owners: make(map[string]string),
+	}
+	if err := conn.ExportMethodTable(map[string]interface{}{
+		"AddMatch": func(rule string) *dbus.Error {
+			return nil
+		},
+		"RemoveMatch": func(rule string) *dbus.Error {
+			return nil
+		},
+		"GetNameOwner": func(name string) (string, *dbus.Error) {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			owner, ok := b.owners[name]
+			if !ok {
+				return "", dbus.NewError("org.freedesktop.DBus.Error.NameHasNoOwner", []interface{}{"no owner for " + name})
+			}
+			return owner, nil
+		},
+	}, testBusPath, testBusInterface); err != nil {
+		t.Fatalf("failed to export test bus: %s", err)
+	}
+	return b
+}
+
+func (b *testBus) setOwner(t *testing.T, name, owner string) {
+	t.Helper()
+	b.mu.Lock()
+	old := b.owners[name]
+	if owner == "" {
+		delete(b.owners, name)
+	} else {
+		b.owners[name] = owner
+	}
+	b.mu.Unlock()
+	if err := b.conn.Emit(testBusPath, testBusInterface+".NameOwnerChanged", name, old, owner); err != nil {
+		t.Fatalf("failed to emit NameOwnerChanged: %s", err)
+	}
+}
+
+func emitTestPropertiesChanged(t *testing.T, conn *dbus.Conn, path dbus.ObjectPath, iface string, changed map[string]dbus.Variant, invalidated []string) {
+	t.Helper()
+	if invalidated == nil {
+		invalidated = []string{}
+	}
+	if err := conn.Emit(path, propertiesSignalPropertiesChanged, iface, changed, invalidated); err != nil {
+		t.Fatalf("failed to emit PropertiesChanged: %s", err)
+	}
+}
+
+func waitForTestCondition(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func sendTestCallAs(conn *dbus.Conn, sender string, path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	i := strings.LastIndex(method, ".")
+	msg := &dbus.Message{
+		Type: dbus.TypeMethodCall,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldPath:      dbus.MakeVariant(path),
+			dbus.FieldInterface: dbus.MakeVariant(method[:i]),
+			dbus.FieldMember:    dbus.MakeVariant(method[i+1:]),
+			dbus.FieldSender:    dbus.MakeVariant(sender),
+		},
+		Body: args,
+	}
+	if len(args) > 0 {
+		msg.Headers[dbus.FieldSignature] = dbus.MakeVariant(dbus.SignatureOf(args...))
+	}
+	return conn.Send(msg, make(chan *dbus.Call, 1))
+}
+
+func waitForTestCall(t *testing.T, call *dbus.Call) *dbus.Call {
+	t.Helper()
+	select {
+	case call = <-call.Done:
+		return call
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", call.Method)
+		return nil
+	}
+}
+
+func callTestAs(t *testing.T, conn *dbus.Conn, sender string, path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	t.Helper()
+	return waitForTestCall(t, sendTestCallAs(conn, sender, path, method, args...))
+}
+
+func assertTestDBusError(t *testing.T, what string, err error, name string) {
+	t.Helper()
+	dbusErr, ok := err.(dbus.Error)
+	if !ok || dbusErr.Name != name {
+		t.Errorf("%s error = %v; want %s", what, err, name)
+	}
+}